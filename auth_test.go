@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRequireBearerToken(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AuthToken = "s3cret"
+	cfg.EnableSnapshot = true
+	registry := prometheus.NewRegistry()
+	handler, err := newHandler(&cfg, registry, registry, func() []*collectorStatus { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer guess", http.StatusUnauthorized},
+		{"wrong scheme", "Basic s3cret", http.StatusUnauthorized},
+		{"right token", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		for _, target := range []struct {
+			name, path string
+			h          http.Handler
+		}{
+			{"wrapped handler", "/", requireBearerToken(cfg.AuthToken, ok)},
+			{"metrics", cfg.MetricsPath, handler},
+			{"snapshot", "/snapshot", handler},
+		} {
+			t.Run(tt.name+" "+target.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, target.path, nil)
+				if tt.authorization != "" {
+					req.Header.Set("Authorization", tt.authorization)
+				}
+				rec := httptest.NewRecorder()
+				target.h.ServeHTTP(rec, req)
+				if rec.Code != tt.want {
+					t.Errorf("got status %d, want %d", rec.Code, tt.want)
+				}
+				challenge := rec.Header().Get("WWW-Authenticate")
+				if tt.want == http.StatusUnauthorized && challenge != `Bearer realm="observator"` {
+					t.Errorf("got WWW-Authenticate %q, want a Bearer challenge", challenge)
+				}
+				if tt.want == http.StatusOK && challenge != "" {
+					t.Errorf("got WWW-Authenticate %q on success", challenge)
+				}
+			})
+		}
+	}
+
+	// The health check and landing page stay open.
+	for _, path := range []string{"/healthz", "/"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s without a token: got status %d, want %d", path, rec.Code, http.StatusOK)
+		}
+	}
+}
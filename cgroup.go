@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cgroupCPU reads the CPU quota and cumulative CPU usage of the cgroup this
+// process runs in, as seen through the cgroup filesystem mounted at root
+// (normally /sys/fs/cgroup, which inside a container is the container's own
+// cgroup).
+//
+// With cgroup v2, detected by the presence of cgroup.controllers at root,
+// the quota comes from cpu.max ("<quota> <period>" or "max <period>") and the
+// usage from usage_usec in cpu.stat. With cgroup v1 the quota comes from
+// cpu/cpu.cfs_quota_us (-1 when unlimited) and cpu/cpu.cfs_period_us, and the
+// usage from cpuacct/cpuacct.usage.
+type cgroupCPU struct {
+	root string
+	v2   bool
+}
+
+// cgroupRoot is where container mode looks for the cgroup filesystem.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// detectCgroupCPU checks that both the quota and the usage of the cgroup can
+// be read, so failures surface at startup rather than on every sample.
+func detectCgroupCPU(root string) (*cgroupCPU, error) {
+	c := &cgroupCPU{root: root}
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		c.v2 = true
+	}
+	if _, err := c.limit(); err != nil {
+		return nil, err
+	}
+	if _, err := c.usage(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// limit returns how many CPUs' worth of time the cgroup may use, or 0 if it
+// has no quota.
+func (c *cgroupCPU) limit() (float64, error) {
+	var quota, period string
+	if c.v2 {
+		data, err := os.ReadFile(filepath.Join(c.root, "cpu.max"))
+		if err != nil {
+			return 0, err
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 {
+			return 0, fmt.Errorf("unexpected cpu.max contents %q", data)
+		}
+		quota, period = fields[0], fields[1]
+		if quota == "max" {
+			return 0, nil
+		}
+	} else {
+		var err error
+		if quota, err = readCgroupValue(filepath.Join(c.root, "cpu", "cpu.cfs_quota_us")); err != nil {
+			return 0, err
+		}
+		if quota == "-1" {
+			return 0, nil
+		}
+		if period, err = readCgroupValue(filepath.Join(c.root, "cpu", "cpu.cfs_period_us")); err != nil {
+			return 0, err
+		}
+	}
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing CPU quota: %w", err)
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, fmt.Errorf("invalid CPU period %q", period)
+	}
+	return q / p, nil
+}
+
+// usage returns the total CPU time consumed by the cgroup so far.
+func (c *cgroupCPU) usage() (time.Duration, error) {
+	if !c.v2 {
+		v, err := readCgroupValue(filepath.Join(c.root, "cpuacct", "cpuacct.usage"))
+		if err != nil {
+			return 0, err
+		}
+		ns, err := strconv.ParseInt(v, 10, 64)
+		return time.Duration(ns), err
+	}
+	data, err := os.ReadFile(filepath.Join(c.root, "cpu.stat"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "usage_usec "); ok {
+			us, err := strconv.ParseInt(v, 10, 64)
+			return time.Duration(us) * time.Microsecond, err
+		}
+	}
+	return 0, errors.New("no usage_usec in cpu.stat")
+}
+
+func readCgroupValue(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	return strings.TrimSpace(string(data)), err
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// writeCgroupFiles lays out a cgroup filesystem under a temporary directory
+// from a map of relative paths to file contents.
+func writeCgroupFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestDetectCgroupCPU(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantV2    bool
+		wantLimit float64
+		wantUsage time.Duration
+	}{
+		{
+			name: "v2 unlimited",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory\n",
+				"cpu.max":            "max 100000\n",
+				"cpu.stat":           "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n",
+			},
+			wantV2:    true,
+			wantUsage: 2500 * time.Millisecond,
+		},
+		{
+			name: "v2 quota",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory\n",
+				"cpu.max":            "150000 100000\n",
+				"cpu.stat":           "usage_usec 42\n",
+			},
+			wantV2:    true,
+			wantLimit: 1.5,
+			wantUsage: 42 * time.Microsecond,
+		},
+		{
+			name: "v1 unlimited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "-1\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+				"cpuacct/cpuacct.usage": "3000000000\n",
+			},
+			wantUsage: 3 * time.Second,
+		},
+		{
+			name: "v1 quota",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "50000\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+				"cpuacct/cpuacct.usage": "1000\n",
+			},
+			wantLimit: 0.5,
+			wantUsage: time.Microsecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := detectCgroupCPU(writeCgroupFiles(t, tt.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.v2 != tt.wantV2 {
+				t.Errorf("v2: got %v, want %v", c.v2, tt.wantV2)
+			}
+			if limit, err := c.limit(); err != nil || limit != tt.wantLimit {
+				t.Errorf("limit: got %v, %v, want %v", limit, err, tt.wantLimit)
+			}
+			if usage, err := c.usage(); err != nil || usage != tt.wantUsage {
+				t.Errorf("usage: got %v, %v, want %v", usage, err, tt.wantUsage)
+			}
+		})
+	}
+}
+
+// An error from detectCgroupCPU makes the CPU collector fall back to host
+// mode, so anything it can't read in full must be reported.
+func TestDetectCgroupCPUErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"no cgroup filesystem", nil},
+		{"v2 without usage_usec", map[string]string{
+			"cgroup.controllers": "cpu\n",
+			"cpu.max":            "max 100000\n",
+			"cpu.stat":           "user_usec 2000000\nsystem_usec 500000\n",
+		}},
+		{"v2 without cpu.max", map[string]string{
+			"cgroup.controllers": "memory\n",
+			"cpu.stat":           "usage_usec 42\n",
+		}},
+		{"v1 without cpuacct", map[string]string{
+			"cpu/cpu.cfs_quota_us":  "-1\n",
+			"cpu/cpu.cfs_period_us": "100000\n",
+		}},
+		{"v1 zero period", map[string]string{
+			"cpu/cpu.cfs_quota_us":  "50000\n",
+			"cpu/cpu.cfs_period_us": "0\n",
+			"cpuacct/cpuacct.usage": "1000\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, err := detectCgroupCPU(writeCgroupFiles(t, tt.files)); err == nil {
+				t.Errorf("got %+v, want an error", c)
+			}
+		})
+	}
+}
+
+func TestCPUCollectorContainerMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		wantCgroup bool
+	}{
+		{"cgroup", map[string]string{
+			"cgroup.controllers": "cpu\n",
+			"cpu.max":            "200000 100000\n",
+			"cpu.stat":           "usage_usec 42\n",
+		}, true},
+		{"fallback to host", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := cgroupRoot
+			cgroupRoot = writeCgroupFiles(t, tt.files)
+			defer func() { cgroupRoot = root }()
+
+			cfg := defaultConfig()
+			cfg.CPUMode = "container"
+			c, err := newCPUCollector(&cfg, prometheus.NewRegistry())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.(*cpuCollector).cgroup != nil; got != tt.wantCgroup {
+				t.Errorf("cgroup accounting: got %v, want %v", got, tt.wantCgroup)
+			}
+			if err := c.Collect(context.Background()); !errors.Is(err, errBaseline) {
+				t.Errorf("first Collect: got %v, want %v", err, errBaseline)
+			}
+			if err := c.Collect(context.Background()); err != nil {
+				t.Errorf("second Collect: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Collector samples one subsystem and publishes the result as metrics.
+type Collector interface {
+	Name() string
+	// Collect takes a single sample and updates the collector's metrics.
+	Collect(ctx context.Context) error
+}
+
+// errUnsupported is returned by a collector factory when its subsystem isn't
+// available on this host. The collector is then skipped with a warning
+// instead of aborting startup. Returned from Collect, it stops the collector
+// for good in the same way.
+var errUnsupported = errors.New("unavailable")
+
+// errNotConfigured is returned by a collector factory when the collector
+// needs settings that weren't given. The collector is then skipped silently.
+var errNotConfigured = errors.New("not configured")
+
+// errBaseline is returned by Collect when it only took the baseline reading
+// that later rates are computed from, and so has published nothing yet. It
+// counts neither as a success nor as a failure.
+var errBaseline = errors.New("baseline reading taken")
+
+// A primer is a Collector whose first Collect only takes a baseline reading,
+// because what it reports is a rate over the time since the previous call.
+// prime takes that baseline.
+type primer interface {
+	prime(ctx context.Context) error
+}
+
+// A closer is a Collector that holds resources beyond its metrics, which
+// close releases once the collector is no longer used.
+type closer interface {
+	close()
+}
+
+// collectorFactory builds a collector, registering its metrics with reg.
+type collectorFactory func(cfg *config, reg prometheus.Registerer) (Collector, error)
+
+// collectorRegistry holds every collector that can be enabled with
+// -collectors. Adding a collector only requires an entry here, or, for one
+// behind a build tag, in an init function of its own file.
+var collectorRegistry = map[string]collectorFactory{
+	"connections": newConnectionsCollector,
+	"cpu":         newCPUCollector,
+	"cputime":     newCPUTimeCollector,
+	"memory":      newMemoryCollector,
+	"swap":        newSwapCollector,
+	"disk":        newDiskCollector,
+	"diskio":      newDiskIOCollector,
+	"exec":        newExecCollector,
+	"network":     newNetworkCollector,
+	"load":        newLoadCollector,
+	"host":        newHostCollector,
+	"process":     newProcessCollector,
+	"temperature": newTemperatureCollector,
+}
+
+func collectorNames() []string {
+	return slices.Sorted(maps.Keys(collectorRegistry))
+}
+
+// newCollector builds the named collector, registering its metrics with reg
+// and, if -max-series is set, limiting them and counting drops in dropped.
+// It returns a nil Collector if the collector is to be skipped: with a
+// warning if it is unavailable on this host, and silently if it lacks
+// required settings.
+func newCollector(cfg *config, name string, reg prometheus.Registerer, dropped *prometheus.CounterVec) (Collector, error) {
+	if dropped != nil {
+		reg = newSeriesLimitRegisterer(reg, name, cfg.MaxSeries, dropped)
+	}
+	ereg := &errorRegisterer{Registerer: reg}
+	c, err := collectorRegistry[name](cfg, ereg)
+	if err == nil && ereg.err != nil {
+		if cl, ok := c.(closer); ok {
+			cl.close()
+		}
+		err = ereg.err
+	}
+	if errors.Is(err, errNotConfigured) {
+		return nil, nil
+	}
+	if errors.Is(err, errUnsupported) {
+		slog.Warn("Skipping collector", "collector", name, "err", err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s collector: %w", name, err)
+	}
+	return c, nil
+}
+
+// newDroppedSeriesCounter returns the counter series dropped for exceeding
+// -max-series are counted in, or nil if series aren't limited.
+func newDroppedSeriesCounter(cfg *config, reg prometheus.Registerer) *prometheus.CounterVec {
+	if cfg.MaxSeries == 0 {
+		return nil
+	}
+	return promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "collector_dropped_series_total",
+		Help: "Total number of series left out for exceeding -max-series, by collector",
+	}, []string{"collector"})
+}
+
+// collectorMetrics are the exporter's own metrics about how its collectors
+// are doing, labeled by collector name.
+type collectorMetrics struct {
+	errors      *prometheus.CounterVec
+	lastSuccess *prometheus.GaugeVec
+}
+
+func newCollectorMetrics(reg prometheus.Registerer) *collectorMetrics {
+	factory := promauto.With(reg)
+	return &collectorMetrics{
+		errors: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "collector_errors_total",
+			Help: "Total number of failed collections per collector",
+		}, []string{"collector"}),
+		lastSuccess: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "collector_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful collection per collector",
+		}, []string{"collector"}),
+	}
+}
+
+// runCollector calls c.Collect every interval until ctx is cancelled. A
+// failed sample is logged and retried on the next tick. ctx is checked before
+// each sample because select picks at random when a tick is also pending.
+//
+// Each call gets a context that expires shortly before the next tick, which
+// leaves a call that was cut short time to wind down. Collectors pass
+// it on to gopsutil, but a call stuck in the kernel, on a hung NFS mount for
+// instance, may not notice; the runner then stops waiting and reports a
+// timeout, and skips further ticks until the stuck call has returned, so a
+// runner never has two calls in flight. Across runners, for a collector
+// restarted while a call is stuck, the collectorManager serializes calls.
+func runCollector(ctx context.Context, c Collector, interval time.Duration, status *collectorStatus, m *collectorMetrics) {
+	failures := m.errors.WithLabelValues(c.Name())
+	lastSuccess := m.lastSuccess.WithLabelValues(c.Name())
+	warnings := newLogLimiter(warnPeriod)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	timeout := collectTimeout(interval)
+	// stuck is set while a timed-out call is still running.
+	var stuck <-chan error
+	for ctx.Err() == nil {
+		if stuck != nil {
+			select {
+			case <-stuck:
+				stuck = nil
+			default:
+				failures.Inc()
+				warnings.warn("Skipping collection, previous one still running", "collector", c.Name())
+			}
+		}
+		if stuck == nil {
+			start := time.Now()
+			var err error
+			stuck, err = collectWithTimeout(ctx, c.Collect, timeout)
+			if ctx.Err() != nil {
+				return
+			}
+			switch {
+			case errors.Is(err, errUnsupported):
+				failures.Inc()
+				slog.Warn("Disabling collector", "collector", c.Name(), "err", err)
+				status.disable()
+				return
+			case errors.Is(err, errBaseline):
+				slog.Debug("Took baseline reading", "collector", c.Name())
+			case err != nil:
+				failures.Inc()
+				warnings.warn("Collection failed", "collector", c.Name(), "err", err)
+			default:
+				status.markUpdated()
+				lastSuccess.SetToCurrentTime()
+				slog.Debug("Collected metrics", "collector", c.Name(), "duration", time.Since(start))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe runs a factory's startup check for the named collector under the
+// timeout its Collect calls get, so that a check that hangs fails the
+// factory instead of holding up startup or a reload.
+func probe(cfg *config, name string, check func(ctx context.Context) error) error {
+	_, err := collectWithTimeout(context.Background(), check, collectTimeout(cfg.collectorInterval(name)))
+	return err
+}
+
+// collectTimeout is how long a single Collect call may take for a collector
+// sampled every interval.
+func collectTimeout(interval time.Duration) time.Duration {
+	return interval - interval/10
+}
+
+// collectWithTimeout calls collect, typically a Collector's Collect, with a
+// context that expires after timeout. A call still running by then is given
+// up on and reported as timed out; pending then receives its result once it
+// does return.
+func collectWithTimeout(ctx context.Context, collect func(context.Context) error, timeout time.Duration) (pending <-chan error, err error) {
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- collect(callCtx)
+	}()
+	select {
+	case err = <-done:
+	case <-callCtx.Done():
+		pending, err = done, callCtx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return pending, err
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type config struct {
+	ListenAddr  string        `yaml:"listen_addr"`
+	MetricsPath string        `yaml:"metrics_path"`
+	Interval    time.Duration `yaml:"interval"`
+	// Intervals overrides Interval for individual collectors, by name.
+	Intervals         map[string]time.Duration `yaml:"intervals"`
+	Collectors        []string                 `yaml:"collectors"`
+	MaxSeries         int                      `yaml:"max_series"`
+	CPUMode           string                   `yaml:"cpu_mode"`
+	DiskIgnoreFSTypes []string                 `yaml:"disk_ignore_fstypes"`
+	DiskIODevices     []string                 `yaml:"disk_io_devices"`
+	ConnStates        []string                 `yaml:"conn_states"`
+	WatchPID          int32                    `yaml:"watch_pid"`
+	WatchName         string                   `yaml:"watch_name"`
+	TLSCert           string                   `yaml:"tls_cert"`
+	TLSKey            string                   `yaml:"tls_key"`
+	TLSMinVersion     string                   `yaml:"tls_min_version"`
+	AuthToken         string                   `yaml:"auth_token"`
+	EnableSnapshot    bool                     `yaml:"enable_snapshot"`
+	LogFormat         string                   `yaml:"log_format"`
+	LogLevel          string                   `yaml:"log_level"`
+	TempSensorFilter  string                   `yaml:"temp_sensor_filter"`
+	PushgatewayURL    string                   `yaml:"pushgateway_url"`
+	PushJob           string                   `yaml:"push_job"`
+	PushLabels        map[string]string        `yaml:"push_labels"`
+	OTLPEndpoint      string                   `yaml:"otlp_endpoint"`
+	Exec              map[string]string        `yaml:"exec"`
+	ExecTimeout       time.Duration            `yaml:"exec_timeout"`
+	Labels            map[string]string        `yaml:"labels"`
+
+	// Once is only settable by flag: it picks a mode of operation rather
+	// than configuring the exporter.
+	Once bool `yaml:"-"`
+	// ConfigPath is the file given by -config, if any, to re-read on reload.
+	ConfigPath string `yaml:"-"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func defaultConfig() config {
+	return config{
+		ListenAddr:        ":8080",
+		Interval:          time.Second,
+		MetricsPath:       "/metrics",
+		Collectors:        collectorNames(),
+		DiskIgnoreFSTypes: []string{"tmpfs", "devtmpfs", "overlay", "squashfs"},
+		TLSMinVersion:     "1.2",
+		CPUMode:           "host",
+		LogFormat:         "text",
+		LogLevel:          "info",
+		PushJob:           "observator",
+		ExecTimeout:       5 * time.Second,
+	}
+}
+
+// parseConfig builds the configuration from the built-in defaults, then the
+// YAML file named by -config (if any), then the environment (see envFlags),
+// then the flags given in args, each layer overriding the previous one.
+func parseConfig(args []string) (*config, error) {
+	cfg := defaultConfig()
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	configPath := fs.String("config", "", "path to a YAML configuration file")
+	showVersion := fs.Bool("version", false, "print version information and exit")
+	fs.BoolVar(&cfg.Once, "once", false, "sample each collector once, print the metrics to stdout and exit")
+	fs.StringVar(&cfg.MetricsPath, "metrics-path", cfg.MetricsPath, "path to serve metrics under")
+	fs.StringVar(&cfg.ListenAddr, "listen-addr", cfg.ListenAddr, "address to serve metrics on: host:port, or unix:/path/to/socket for a Unix domain socket")
+	fs.DurationVar(&cfg.Interval, "interval", cfg.Interval, "sampling interval for all collectors")
+	fs.Var((*durationMapFlag)(&cfg.Intervals), "collector-interval", "sampling interval for one collector as name=duration, overriding -interval (repeatable)")
+	fs.Var((*listFlag)(&cfg.Collectors), "collectors", "comma-separated collectors to enable")
+	fs.IntVar(&cfg.MaxSeries, "max-series", cfg.MaxSeries, "maximum number of label combinations each labeled metric may report (0 means unlimited)")
+	fs.StringVar(&cfg.CPUMode, "cpu-mode", cfg.CPUMode, "what cpu_usage_percent measures: host, or container to report this cgroup's usage against its CPU quota")
+	fs.Var((*listFlag)(&cfg.DiskIgnoreFSTypes), "disk-ignore-fstypes", "comma-separated filesystem types to exclude from disk metrics")
+	fs.Var((*listFlag)(&cfg.DiskIODevices), "disk-io-devices", "comma-separated block devices to report I/O for (default all)")
+	fs.Var((*listFlag)(&cfg.ConnStates), "conn-states", "comma-separated TCP states to report connection counts for (default all)")
+	fs.Func("watch-pid", "PID of a process to report metrics for", func(s string) error {
+		pid, err := strconv.ParseInt(s, 10, 32)
+		cfg.WatchPID = int32(pid)
+		return err
+	})
+	fs.StringVar(&cfg.WatchName, "watch-name", cfg.WatchName, "executable name of a process to report metrics for")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "path to a TLS certificate; serves HTTPS together with -tls-key")
+	fs.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "path to the TLS private key for -tls-cert")
+	fs.StringVar(&cfg.TLSMinVersion, "tls-min-version", cfg.TLSMinVersion, "minimum TLS version to accept (1.0, 1.1, 1.2 or 1.3)")
+	fs.StringVar(&cfg.AuthToken, "auth-token", cfg.AuthToken, "require this bearer token on metrics requests")
+	fs.BoolVar(&cfg.EnableSnapshot, "enable-snapshot", cfg.EnableSnapshot, "serve the current metric values as JSON on /snapshot, for debugging")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "log output format (text or json)")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "minimum level to log (debug, info, warn or error)")
+	fs.StringVar(&cfg.TempSensorFilter, "temp-sensor-filter", cfg.TempSensorFilter, "regular expression selecting which temperature sensors to report")
+	fs.StringVar(&cfg.PushgatewayURL, "pushgateway-url", cfg.PushgatewayURL, "also push metrics to the Pushgateway at this URL every interval")
+	fs.StringVar(&cfg.PushJob, "push-job", cfg.PushJob, "job name to push metrics under")
+	fs.Var((*mapFlag)(&cfg.PushLabels), "push-label", "grouping label for pushed metrics as name=value (repeatable)")
+	fs.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", cfg.OTLPEndpoint, "also export metrics over OTLP/HTTP to this URL every interval (e.g. http://localhost:4318)")
+	fs.Var((*mapFlag)(&cfg.Exec), "exec", "report the number printed by a command as a gauge, given as metric_name=command (repeatable); the command is run directly, not through a shell")
+	fs.DurationVar(&cfg.ExecTimeout, "exec-timeout", cfg.ExecTimeout, "how long -exec commands may run before being killed; the exec collector's interval caps it as well")
+	fs.Var((*mapFlag)(&cfg.Labels), "label", "static label added to every metric as name=value (repeatable)")
+	fs.Parse(args)
+	if *showVersion {
+		fmt.Println(versionString())
+		os.Exit(0)
+	}
+
+	cfg.ConfigPath = *configPath
+	if *configPath != "" {
+		if err := cfg.load(*configPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(fs); err != nil {
+		return nil, err
+	}
+	// Parse again so that flags given on the command line take precedence
+	// over the values just read from the file and the environment.
+	fs.Parse(args)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// envFlags maps the environment variables that can stand in for a flag,
+// which is handier than flags when running in a container.
+var envFlags = []struct{ env, flag string }{
+	{"OBSERVATOR_LISTEN_ADDR", "listen-addr"},
+	{"OBSERVATOR_INTERVAL", "interval"},
+	{"OBSERVATOR_COLLECTORS", "collectors"},
+}
+
+// applyEnv sets the flags in fs from those of envFlags present in the
+// environment, parsing each value exactly as the flag itself would.
+func applyEnv(fs *flag.FlagSet) error {
+	for _, ef := range envFlags {
+		v, ok := os.LookupEnv(ef.env)
+		if !ok {
+			continue
+		}
+		if err := fs.Set(ef.flag, v); err != nil {
+			return fmt.Errorf("invalid value %q for $%s: %w", v, ef.env, err)
+		}
+	}
+	return nil
+}
+
+// load overlays the settings found in the YAML file at path onto c. Keys
+// that don't correspond to a setting are rejected so typos don't go unnoticed.
+func (c *config) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *config) validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("invalid interval %s: must be positive", c.Interval)
+	}
+	switch {
+	case !strings.HasPrefix(c.MetricsPath, "/"):
+		return fmt.Errorf("invalid metrics path %q: must start with /", c.MetricsPath)
+	case c.MetricsPath == "/", c.MetricsPath == "/healthz", c.MetricsPath == "/snapshot":
+		return fmt.Errorf("invalid metrics path %q: already taken by another endpoint", c.MetricsPath)
+	case strings.ContainsAny(c.MetricsPath, "{} "):
+		return fmt.Errorf("invalid metrics path %q", c.MetricsPath)
+	}
+	if c.MaxSeries < 0 {
+		return fmt.Errorf("invalid max series %d: must not be negative", c.MaxSeries)
+	}
+	if err := validateListenAddr(c.ListenAddr); err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", c.ListenAddr, err)
+	}
+	if c.WatchPID < 0 {
+		return fmt.Errorf("invalid watch PID %d", c.WatchPID)
+	}
+	if c.WatchPID != 0 && c.WatchName != "" {
+		return errors.New("watch PID and watch name are mutually exclusive")
+	}
+	if c.CPUMode != "host" && c.CPUMode != "container" {
+		return fmt.Errorf("invalid CPU mode %q (want host or container)", c.CPUMode)
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("TLS certificate and key must be given together")
+	}
+	if _, ok := tlsVersions[c.TLSMinVersion]; !ok {
+		return fmt.Errorf("invalid TLS minimum version %q (want 1.0, 1.1, 1.2 or 1.3)", c.TLSMinVersion)
+	}
+	if _, err := newLogger(io.Discard, c.LogFormat, c.LogLevel); err != nil {
+		return err
+	}
+	if _, err := regexp.Compile(c.TempSensorFilter); err != nil {
+		return fmt.Errorf("invalid temperature sensor filter: %w", err)
+	}
+	if c.PushgatewayURL != "" {
+		u, err := url.Parse(c.PushgatewayURL)
+		if err != nil {
+			return fmt.Errorf("invalid Pushgateway URL: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid Pushgateway URL %q: scheme must be http or https", c.PushgatewayURL)
+		}
+		if c.PushJob == "" {
+			return errors.New("push job name must not be empty")
+		}
+	}
+	if c.OTLPEndpoint != "" {
+		u, err := url.Parse(c.OTLPEndpoint)
+		if err != nil {
+			return fmt.Errorf("invalid OTLP endpoint: %w", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid OTLP endpoint %q: want an http:// or https:// URL", c.OTLPEndpoint)
+		}
+	}
+	for name := range c.Labels {
+		if !validLabelName(name) {
+			return fmt.Errorf("invalid label name %q: must match %s and not start with __", name, labelNameRE)
+		}
+	}
+	for name := range c.PushLabels {
+		if !validLabelName(name) {
+			return fmt.Errorf("invalid push label name %q", name)
+		}
+	}
+	for name, command := range c.Exec {
+		if !metricNameRE.MatchString(name) {
+			return fmt.Errorf("invalid exec metric name %q: must match %s", name, metricNameRE)
+		}
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("empty command for exec metric %s", name)
+		}
+	}
+	if c.ExecTimeout <= 0 {
+		return fmt.Errorf("invalid exec timeout %s: must be positive", c.ExecTimeout)
+	}
+	for _, name := range c.Collectors {
+		if _, ok := collectorRegistry[name]; !ok {
+			return fmt.Errorf("unknown collector %q (known: %s)", name, strings.Join(collectorNames(), ", "))
+		}
+	}
+	for name, d := range c.Intervals {
+		if _, ok := collectorRegistry[name]; !ok {
+			return fmt.Errorf("interval given for unknown collector %q", name)
+		}
+		if d <= 0 {
+			return fmt.Errorf("invalid interval %s for collector %s: must be positive", d, name)
+		}
+	}
+	return nil
+}
+
+// collectorInterval returns how often the named collector samples.
+func (c *config) collectorInterval(name string) time.Duration {
+	if d, ok := c.Intervals[name]; ok {
+		return d
+	}
+	return c.Interval
+}
+
+// listFlag is a comma-separated list of values; setting it replaces the
+// previous contents rather than appending to them.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = nil
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// mapFlag collects repeated name=value flags into a map.
+type mapFlag map[string]string
+
+func (m *mapFlag) String() string {
+	var pairs []string
+	for name, value := range *m {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m *mapFlag) Set(value string) error {
+	name, v, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("%q is not in name=value form", value)
+	}
+	if *m == nil {
+		*m = map[string]string{}
+	}
+	(*m)[name] = v
+	return nil
+}
+
+// durationMapFlag is a set of name=duration pairs, one per use of the flag.
+type durationMapFlag map[string]time.Duration
+
+func (m *durationMapFlag) String() string {
+	var pairs []string
+	for name, d := range *m {
+		pairs = append(pairs, name+"="+d.String())
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m *durationMapFlag) Set(value string) error {
+	name, v, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("%q is not in name=duration form", value)
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	if *m == nil {
+		*m = map[string]time.Duration{}
+	}
+	(*m)[name] = d
+	return nil
+}
+
+var (
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+)
+
+// validLabelName reports whether name is a valid Prometheus label name.
+// Names starting with "__" are reserved for internal use.
+func validLabelName(name string) bool {
+	return labelNameRE.MatchString(name) && !strings.HasPrefix(name, "__")
+}
+
+// validateListenAddr catches malformed addresses up front so they are
+// reported as such instead of as a generic bind failure.
+func validateListenAddr(addr string) error {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if path == "" {
+			return errors.New("missing socket path")
+		}
+		return nil
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := net.LookupPort("tcp", port); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes a YAML configuration file and returns its path.
+func writeConfigFile(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "observator.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseConfigLayers(t *testing.T) {
+	path := writeConfigFile(t, `
+listen_addr: ":9000"
+interval: 10s
+collectors: [cpu, memory, disk]
+labels:
+  env: prod
+  region: eu
+`)
+	tests := []struct {
+		name  string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, cfg *config)
+	}{
+		{"file", nil, nil, func(t *testing.T, cfg *config) {
+			if cfg.ListenAddr != ":9000" {
+				t.Errorf("listen_addr: got %q, want %q", cfg.ListenAddr, ":9000")
+			}
+			if cfg.MetricsPath != "/metrics" {
+				t.Errorf("metrics_path: got %q, want the default", cfg.MetricsPath)
+			}
+		}},
+		{"flag overrides file", nil, []string{"-listen-addr", ":9100", "-interval", "5s"}, func(t *testing.T, cfg *config) {
+			if cfg.ListenAddr != ":9100" {
+				t.Errorf("listen_addr: got %q, want %q", cfg.ListenAddr, ":9100")
+			}
+			if cfg.Interval != 5*time.Second {
+				t.Errorf("interval: got %v, want %v", cfg.Interval, 5*time.Second)
+			}
+		}},
+		{"labels merge", nil, []string{"-label", "region=us", "-label", "team=infra"}, func(t *testing.T, cfg *config) {
+			want := map[string]string{"env": "prod", "region": "us", "team": "infra"}
+			if !maps.Equal(cfg.Labels, want) {
+				t.Errorf("labels: got %v, want %v", cfg.Labels, want)
+			}
+		}},
+		{"collectors replaced", nil, []string{"-collectors", "load"}, func(t *testing.T, cfg *config) {
+			if want := []string{"load"}; !slices.Equal(cfg.Collectors, want) {
+				t.Errorf("collectors: got %v, want %v", cfg.Collectors, want)
+			}
+		}},
+		{"env overrides file", map[string]string{
+			"OBSERVATOR_LISTEN_ADDR": ":9200",
+			"OBSERVATOR_INTERVAL":    "20s",
+			"OBSERVATOR_COLLECTORS":  "swap,load",
+		}, nil, func(t *testing.T, cfg *config) {
+			if cfg.ListenAddr != ":9200" {
+				t.Errorf("listen_addr: got %q, want %q", cfg.ListenAddr, ":9200")
+			}
+			if cfg.Interval != 20*time.Second {
+				t.Errorf("interval: got %v, want %v", cfg.Interval, 20*time.Second)
+			}
+			if want := []string{"swap", "load"}; !slices.Equal(cfg.Collectors, want) {
+				t.Errorf("collectors: got %v, want %v", cfg.Collectors, want)
+			}
+		}},
+		{"flag overrides env", map[string]string{
+			"OBSERVATOR_LISTEN_ADDR": ":9200",
+			"OBSERVATOR_COLLECTORS":  "swap",
+		}, []string{"-listen-addr", ":9100", "-collectors", "load"}, func(t *testing.T, cfg *config) {
+			if cfg.ListenAddr != ":9100" {
+				t.Errorf("listen_addr: got %q, want %q", cfg.ListenAddr, ":9100")
+			}
+			if want := []string{"load"}; !slices.Equal(cfg.Collectors, want) {
+				t.Errorf("collectors: got %v, want %v", cfg.Collectors, want)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := parseConfig(append([]string{"-config", path}, tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{"unknown key", "listen_adr: \":9000\"\n", nil, "field listen_adr not found"},
+		{"invalid env value", "", map[string]string{"OBSERVATOR_INTERVAL": "soon"}, "$OBSERVATOR_INTERVAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := parseConfig([]string{"-config", writeConfigFile(t, tt.file)})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/net"
+)
+
+type connectionsCollector struct {
+	// states is the allowlist from -conn-states; nil means all states.
+	states      map[string]bool
+	connections *prometheus.GaugeVec
+	// known holds every state reported so far, so a state whose last
+	// connection has closed drops to zero instead of keeping its old count.
+	known map[string]bool
+}
+
+func newConnectionsCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	c := &connectionsCollector{known: map[string]bool{}}
+	if len(cfg.ConnStates) > 0 {
+		c.states = make(map[string]bool, len(cfg.ConnStates))
+		for _, s := range cfg.ConnStates {
+			c.states[strings.ToUpper(s)] = true
+		}
+	}
+	err := probe(cfg, c.Name(), func(ctx context.Context) error {
+		_, err := connections(ctx)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errUnsupported) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("listing TCP connections: %w", err)
+	}
+	c.connections = promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "network_connections",
+		Help: "Number of TCP connections per state",
+	}, []string{"state"})
+	return c, nil
+}
+
+func (c *connectionsCollector) Name() string { return "connections" }
+
+func (c *connectionsCollector) Collect(ctx context.Context) error {
+	conns, err := connections(ctx)
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	for _, conn := range conns {
+		if c.states == nil || c.states[conn.Status] {
+			counts[conn.Status]++
+		}
+	}
+	for state := range c.known {
+		if _, ok := counts[state]; !ok {
+			c.connections.WithLabelValues(state).Set(0)
+		}
+	}
+	for state, n := range counts {
+		c.known[state] = true
+		c.connections.WithLabelValues(state).Set(float64(n))
+	}
+	return nil
+}
+
+// connections lists TCP connections, turning a permission failure into
+// errUnsupported with a hint, as retrying won't help until the exporter is
+// given more privileges.
+func connections(ctx context.Context) ([]net.ConnectionStat, error) {
+	conns, err := net.ConnectionsWithContext(ctx, "tcp")
+	if errors.Is(err, os.ErrPermission) {
+		return nil, fmt.Errorf("%w: %v (listing other users' connections needs elevated privileges, e.g. root or CAP_SYS_PTRACE)", errUnsupported, err)
+	}
+	return conns, err
+}
@@ -0,0 +1,28 @@
+package main
+
+// counterTracker turns absolute, monotonically increasing kernel counters
+// into increments for Prometheus counters. A reading lower than the previous
+// one means the source counter was reset or wrapped; the new reading is then
+// counted from zero instead of being subtracted, so nothing is counted twice.
+type counterTracker struct {
+	last map[string]uint64
+}
+
+func newCounterTracker() *counterTracker {
+	return &counterTracker{last: map[string]uint64{}}
+}
+
+// delta records value as the latest reading for key and returns how much the
+// counter advanced since the previous reading.
+func (t *counterTracker) delta(key string, value uint64) float64 {
+	prev, ok := t.last[key]
+	t.last[key] = value
+	if !ok || value < prev {
+		return float64(value)
+	}
+	return float64(value - prev)
+}
+
+func (t *counterTracker) forget(key string) {
+	delete(t.last, key)
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestCounterTrackerDelta(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value uint64
+		// forget drops key before the reading is taken.
+		forget bool
+		want   float64
+	}{
+		{name: "first reading", key: "eth0", value: 100, want: 100},
+		{name: "increase", key: "eth0", value: 150, want: 50},
+		{name: "unchanged", key: "eth0", value: 150, want: 0},
+		{name: "reset", key: "eth0", value: 20, want: 20},
+		{name: "after reset", key: "eth0", value: 30, want: 10},
+		{name: "other key", key: "eth1", value: 5, want: 5},
+		{name: "forgotten", key: "eth0", value: 40, forget: true, want: 40},
+	}
+	tracker := newCounterTracker()
+	for _, tt := range tests {
+		if tt.forget {
+			tracker.forget(tt.key)
+		}
+		if got := tracker.delta(tt.key, tt.value); got != tt.want {
+			t.Errorf("%s: delta(%q, %d) = %v, want %v", tt.name, tt.key, tt.value, got, tt.want)
+		}
+	}
+}
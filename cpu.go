@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/cpu"
+)
+
+type cpuCollector struct {
+	usage prometheus.Gauge
+	// cores holds the per-core label sets, resolved once at startup so
+	// Collect doesn't have to look them up on every tick.
+	cores []prometheus.Gauge
+	// primed is set once the first reading has been taken and discarded.
+	primed bool
+
+	// cgroup is set in container mode, where cpu_usage_percent reports the
+	// cgroup's own usage relative to its CPU quota instead of host usage.
+	// lastUsage and lastRead hold the previous reading to take deltas from.
+	cgroup    *cgroupCPU
+	lastUsage time.Duration
+	lastRead  time.Time
+}
+
+func newCPUCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	n, err := cpu.Counts(true)
+	if err != nil {
+		return nil, err
+	}
+	factory := promauto.With(reg)
+	c := &cpuCollector{
+		usage: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "cpu_usage_percent",
+			Help: "Current CPU usage in percent",
+		}),
+		cores: make([]prometheus.Gauge, n),
+	}
+	coreUsage := factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cpu_core_usage_percent",
+		Help: "Current CPU usage per core in percent",
+	}, []string{"core"})
+	for i := range c.cores {
+		c.cores[i] = coreUsage.WithLabelValues(strconv.Itoa(i))
+	}
+	if cfg.CPUMode == "container" {
+		if c.cgroup, err = detectCgroupCPU(cgroupRoot); err != nil {
+			slog.Warn("Cgroup CPU accounting unavailable, falling back to host CPU mode", "err", err)
+		}
+	}
+	return c, nil
+}
+
+func (c *cpuCollector) Name() string { return "cpu" }
+
+// Collect reports usage since the previous call without blocking, so the
+// runner's ticker alone sets the sampling cadence. The very first reading
+// measures from whenever gopsutil happened to take its initial snapshot, so
+// it is thrown away rather than published.
+func (c *cpuCollector) Collect(ctx context.Context) error {
+	percent, err := cpu.PercentWithContext(ctx, 0, true)
+	if err != nil {
+		return err
+	}
+	if len(percent) == 0 {
+		// gopsutil can come back empty-handed without an error; averaging
+		// nothing would publish NaN, so skip the tick and let the runner
+		// log it.
+		return errors.New("no per-CPU usage reported")
+	}
+	var containerPercent float64
+	if c.cgroup != nil {
+		if containerPercent, err = c.containerUsage(); err != nil {
+			return err
+		}
+	}
+	if !c.primed {
+		c.primed = true
+		return errBaseline
+	}
+	var total float64
+	for i, p := range percent {
+		if i < len(c.cores) {
+			c.cores[i].Set(p)
+		}
+		total += p
+	}
+	if c.cgroup != nil {
+		c.usage.Set(containerPercent)
+	} else {
+		c.usage.Set(total / float64(len(percent)))
+	}
+	return nil
+}
+
+// prime relies on Collect discarding its first reading.
+func (c *cpuCollector) prime(ctx context.Context) error { return c.Collect(ctx) }
+
+// containerUsage returns the cgroup's CPU usage since the previous call as a
+// percentage of its quota, or of all host CPUs if it has no quota.
+func (c *cpuCollector) containerUsage() (float64, error) {
+	used, err := c.cgroup.usage()
+	if err != nil {
+		return 0, err
+	}
+	limit, err := c.cgroup.limit()
+	if err != nil {
+		return 0, err
+	}
+	if limit == 0 {
+		limit = float64(runtime.NumCPU())
+	}
+	now := time.Now()
+	var percent float64
+	if elapsed := now.Sub(c.lastRead); !c.lastRead.IsZero() && elapsed > 0 {
+		percent = (used - c.lastUsage).Seconds() / elapsed.Seconds() / limit * 100
+	}
+	c.lastUsage, c.lastRead = used, now
+	return percent, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/cpu"
+)
+
+type cpuTimeCollector struct {
+	seconds *prometheus.CounterVec
+	// last holds the previous reading per cpu and mode. Unlike the counters
+	// counterTracker handles, CPU times don't reset, but the kernel's iowait
+	// figure is known to dip slightly now and then; a lower reading is
+	// therefore ignored rather than taken as a reset.
+	last map[[2]string]float64
+}
+
+func newCPUTimeCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	return &cpuTimeCollector{
+		seconds: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cpu_seconds_total",
+			Help: "Seconds the CPUs spent in each mode",
+		}, []string{"cpu", "mode"}),
+		last: map[[2]string]float64{},
+	}, nil
+}
+
+func (c *cpuTimeCollector) Name() string { return "cputime" }
+
+func (c *cpuTimeCollector) Collect(ctx context.Context) error {
+	times, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return err
+	}
+	for _, t := range times {
+		// gopsutil names the cores "cpu0", "cpu1", ...; label them "0",
+		// "1", ... as node_exporter does so existing queries carry over.
+		core := strings.TrimPrefix(t.CPU, "cpu")
+		for mode, v := range map[string]float64{
+			"user":    t.User,
+			"nice":    t.Nice,
+			"system":  t.System,
+			"idle":    t.Idle,
+			"iowait":  t.Iowait,
+			"irq":     t.Irq,
+			"softirq": t.Softirq,
+			"steal":   t.Steal,
+		} {
+			key := [2]string{core, mode}
+			prev, ok := c.last[key]
+			if ok && v <= prev {
+				continue
+			}
+			c.last[key] = v
+			c.seconds.WithLabelValues(core, mode).Add(v - prev)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/disk"
+)
+
+type mount struct {
+	mountpoint, fstype string
+}
+
+type diskCollector struct {
+	ignored                  map[string]bool
+	total, used, usedPercent *prometheus.GaugeVec
+	// known holds the mounts reported on the previous sample, so series for
+	// filesystems that have since been unmounted can be dropped.
+	known    map[mount]bool
+	warnings *logLimiter
+}
+
+func newDiskCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	labels := []string{"mountpoint", "fstype"}
+	factory := promauto.With(reg)
+	c := &diskCollector{
+		ignored: make(map[string]bool, len(cfg.DiskIgnoreFSTypes)),
+		total: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "disk_total_bytes",
+			Help: "Total size of the filesystem in bytes",
+		}, labels),
+		used: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "disk_used_bytes",
+			Help: "Used space on the filesystem in bytes",
+		}, labels),
+		usedPercent: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "disk_used_percent",
+			Help: "Used space on the filesystem in percent",
+		}, labels),
+		known:    map[mount]bool{},
+		warnings: newLogLimiter(warnPeriod),
+	}
+	for _, t := range cfg.DiskIgnoreFSTypes {
+		c.ignored[t] = true
+	}
+	return c, nil
+}
+
+func (c *diskCollector) Name() string { return "disk" }
+
+// Collect re-reads the partition table on every call so filesystems mounted
+// at runtime show up.
+func (c *diskCollector) Collect(ctx context.Context) error {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return err
+	}
+	seen := map[mount]bool{}
+	for _, p := range partitions {
+		if c.ignored[p.Fstype] {
+			continue
+		}
+		usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil {
+			c.warnings.warn("Disk usage unavailable", "mountpoint", p.Mountpoint, "err", err)
+			continue
+		}
+		m := mount{p.Mountpoint, p.Fstype}
+		seen[m] = true
+		c.total.WithLabelValues(m.mountpoint, m.fstype).Set(float64(usage.Total))
+		c.used.WithLabelValues(m.mountpoint, m.fstype).Set(float64(usage.Used))
+		c.usedPercent.WithLabelValues(m.mountpoint, m.fstype).Set(usage.UsedPercent)
+	}
+	for m := range c.known {
+		if !seen[m] {
+			c.total.DeleteLabelValues(m.mountpoint, m.fstype)
+			c.used.DeleteLabelValues(m.mountpoint, m.fstype)
+			c.usedPercent.DeleteLabelValues(m.mountpoint, m.fstype)
+		}
+	}
+	c.known = seen
+	return nil
+}
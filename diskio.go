@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/disk"
+)
+
+type diskIOCollector struct {
+	// devices is the allowlist from -disk-io-devices; nil means all devices.
+	devices map[string]bool
+
+	readBytes, writeBytes, reads, writes *prometheus.CounterVec
+	readBytesSeen, writeBytesSeen        *counterTracker
+	readsSeen, writesSeen                *counterTracker
+	// known holds the devices reported on the previous sample, so series for
+	// devices that have since been removed can be dropped.
+	known map[string]bool
+}
+
+func newDiskIOCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	factory := promauto.With(reg)
+	c := &diskIOCollector{
+		readBytes: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "disk_read_bytes_total",
+			Help: "Total bytes read per block device",
+		}, []string{"device"}),
+		writeBytes: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "disk_write_bytes_total",
+			Help: "Total bytes written per block device",
+		}, []string{"device"}),
+		reads: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "disk_reads_total",
+			Help: "Total read operations completed per block device",
+		}, []string{"device"}),
+		writes: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "disk_writes_total",
+			Help: "Total write operations completed per block device",
+		}, []string{"device"}),
+		readBytesSeen:  newCounterTracker(),
+		writeBytesSeen: newCounterTracker(),
+		readsSeen:      newCounterTracker(),
+		writesSeen:     newCounterTracker(),
+		known:          map[string]bool{},
+	}
+	if len(cfg.DiskIODevices) > 0 {
+		c.devices = make(map[string]bool, len(cfg.DiskIODevices))
+		for _, d := range cfg.DiskIODevices {
+			c.devices[d] = true
+		}
+	}
+	return c, nil
+}
+
+func (c *diskIOCollector) Name() string { return "diskio" }
+
+func (c *diskIOCollector) Collect(ctx context.Context) error {
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for device, ioc := range counters {
+		if c.devices != nil && !c.devices[device] {
+			continue
+		}
+		seen[device] = true
+		c.readBytes.WithLabelValues(device).Add(c.readBytesSeen.delta(device, ioc.ReadBytes))
+		c.writeBytes.WithLabelValues(device).Add(c.writeBytesSeen.delta(device, ioc.WriteBytes))
+		c.reads.WithLabelValues(device).Add(c.readsSeen.delta(device, ioc.ReadCount))
+		c.writes.WithLabelValues(device).Add(c.writesSeen.delta(device, ioc.WriteCount))
+	}
+	for device := range c.known {
+		if !seen[device] {
+			c.readBytes.DeleteLabelValues(device)
+			c.writeBytes.DeleteLabelValues(device)
+			c.reads.DeleteLabelValues(device)
+			c.writes.DeleteLabelValues(device)
+			c.readBytesSeen.forget(device)
+			c.writeBytesSeen.forget(device)
+			c.readsSeen.forget(device)
+			c.writesSeen.forget(device)
+		}
+	}
+	c.known = seen
+	return nil
+}
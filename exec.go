@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// execCollector runs external commands and reports the number each prints
+// as a gauge, for metrics gopsutil doesn't provide (power draw from
+// ipmitool, say).
+type execCollector struct {
+	commands []execCommand
+	timeout  time.Duration
+}
+
+type execCommand struct {
+	name  string
+	args  []string
+	gauge prometheus.Gauge
+}
+
+func newExecCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	if len(cfg.Exec) == 0 {
+		return nil, errNotConfigured
+	}
+	c := &execCollector{timeout: cfg.ExecTimeout}
+	for name, command := range cfg.Exec {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: name,
+			Help: fmt.Sprintf("Value printed by %s", command),
+		})
+		if err := reg.Register(gauge); err != nil {
+			return nil, fmt.Errorf("metric %s: %w", name, err)
+		}
+		c.commands = append(c.commands, execCommand{name: name, args: strings.Fields(command), gauge: gauge})
+	}
+	slices.SortFunc(c.commands, func(a, b execCommand) int { return strings.Compare(a.name, b.name) })
+	return c, nil
+}
+
+func (c *execCollector) Name() string { return "exec" }
+
+// Collect runs all the commands concurrently, so that a slow one doesn't
+// hold up the rest. A command that fails, times out or prints something
+// other than a number leaves its gauge at the previous value.
+func (c *execCollector) Collect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	errs := make([]error, len(c.commands))
+	var wg sync.WaitGroup
+	for i, cmd := range c.commands {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := cmd.run(ctx)
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = errors.New("timed out")
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", cmd.name, err)
+				return
+			}
+			cmd.gauge.Set(v)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (c execCommand) run(ctx context.Context) (float64, error) {
+	cmd := exec.CommandContext(ctx, c.args[0], c.args[1:]...)
+	// Don't wait indefinitely on output pipes a killed command's own
+	// children may still hold open.
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return 0, fmt.Errorf("%w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+		}
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing output: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,112 @@
+//go:build nvml
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The gpu collector only exists in builds with the nvml tag, which keeps cgo
+// and the go-nvml dependency out of the default binary; asking for it in any
+// other build fails as an unknown collector.
+func init() {
+	collectorRegistry["gpu"] = newGPUCollector
+}
+
+// gpuCollector reports NVIDIA GPUs through NVML. libnvidia-ml is loaded at
+// runtime, so a binary built with the nvml tag still runs on hosts without
+// it; the collector is then skipped.
+type gpuCollector struct {
+	devices                       []gpuDevice
+	utilization, memoryUsed, temp *prometheus.GaugeVec
+}
+
+type gpuDevice struct {
+	handle nvml.Device
+	// labels are the device's index and UUID.
+	labels []string
+}
+
+func newGPUCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	if ret := nvml.Init(); ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("%w: initializing NVML: %v", errUnsupported, ret)
+	}
+	devices, err := gpuDevices()
+	if err != nil {
+		nvml.Shutdown()
+		return nil, err
+	}
+	c := &gpuCollector{devices: devices}
+
+	labels := []string{"gpu", "uuid"}
+	factory := promauto.With(reg)
+	c.utilization = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpu_utilization_percent",
+		Help: "Percent of time over the last sample period the GPU was busy",
+	}, labels)
+	c.memoryUsed = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpu_memory_used_bytes",
+		Help: "GPU memory in use in bytes",
+	}, labels)
+	c.temp = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpu_temperature_celsius",
+		Help: "GPU core temperature in degrees Celsius",
+	}, labels)
+	return c, nil
+}
+
+func gpuDevices() ([]gpuDevice, error) {
+	n, ret := nvml.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("counting GPUs: %v", ret)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("%w: no GPUs found", errUnsupported)
+	}
+	var devices []gpuDevice
+	for i := range n {
+		handle, ret := nvml.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("GPU %d: %v", i, ret)
+		}
+		uuid, ret := handle.GetUUID()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("GPU %d: reading UUID: %v", i, ret)
+		}
+		devices = append(devices, gpuDevice{handle: handle, labels: []string{strconv.Itoa(i), uuid}})
+	}
+	return devices, nil
+}
+
+func (c *gpuCollector) Name() string { return "gpu" }
+
+// close shuts NVML down again, so that a collector disabled on reload and
+// later re-enabled starts from a fresh nvml.Init.
+func (c *gpuCollector) close() { nvml.Shutdown() }
+
+func (c *gpuCollector) Collect(ctx context.Context) error {
+	for _, d := range c.devices {
+		util, ret := d.handle.GetUtilizationRates()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("GPU %s: reading utilization: %v", d.labels[0], ret)
+		}
+		memory, ret := d.handle.GetMemoryInfo()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("GPU %s: reading memory usage: %v", d.labels[0], ret)
+		}
+		temp, ret := d.handle.GetTemperature(nvml.TEMPERATURE_GPU)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("GPU %s: reading temperature: %v", d.labels[0], ret)
+		}
+		c.utilization.WithLabelValues(d.labels...).Set(float64(util.Gpu))
+		c.memoryUsed.WithLabelValues(d.labels...).Set(float64(memory.Used))
+		c.temp.WithLabelValues(d.labels...).Set(float64(temp))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// collectorStatus records when a collector last completed a successful
+// sample, so the health check can tell a live collector from a stuck one.
+type collectorStatus struct {
+	name     string
+	interval time.Duration
+
+	mu         sync.Mutex
+	lastUpdate time.Time
+	// ready is set by the first successful sample; lastUpdate alone can't
+	// tell, as it starts out at creation time.
+	ready bool
+	// disabled is set when the collector has stopped itself because its
+	// subsystem turned out to be unavailable; it is no longer checked.
+	disabled bool
+}
+
+func newCollectorStatus(name string, interval time.Duration) *collectorStatus {
+	// Start the clock at creation so a collector isn't reported stale before
+	// it has had a chance to produce its first sample.
+	return &collectorStatus{name: name, interval: interval, lastUpdate: time.Now()}
+}
+
+func (s *collectorStatus) markUpdated() {
+	s.mu.Lock()
+	s.lastUpdate = time.Now()
+	s.ready = true
+	s.mu.Unlock()
+}
+
+func (s *collectorStatus) disable() {
+	s.mu.Lock()
+	s.disabled = true
+	s.mu.Unlock()
+}
+
+// stale reports how long ago the collector last updated, and whether that
+// is longer than three of its intervals.
+func (s *collectorStatus) stale() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	age := time.Since(s.lastUpdate)
+	return age, !s.disabled && age > 3*s.interval
+}
+
+// isReady reports whether the collector has produced a sample yet, or will
+// never produce one because it is disabled.
+func (s *collectorStatus) isReady() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ready || s.disabled
+}
+
+// healthHandler answers 200 "ok" while every collector has updated within
+// three of its intervals, and 503 naming the first stale collector otherwise.
+// With the "ready" query parameter it is a readiness check instead,
+// answering 503 until every collector has completed its first sample, so
+// that the zeros metrics start out at aren't scraped after a restart.
+//
+// statuses is called on every request, as the set of collectors can change
+// when the configuration is reloaded.
+func healthHandler(statuses func() []*collectorStatus) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("ready") {
+			for _, s := range statuses() {
+				if !s.isReady() {
+					http.Error(w, fmt.Sprintf("%s collector not ready", s.name), http.StatusServiceUnavailable)
+					return
+				}
+			}
+			fmt.Fprintln(w, "ok")
+			return
+		}
+		for _, s := range statuses() {
+			if age, stale := s.stale(); stale {
+				http.Error(w, fmt.Sprintf("%s collector stale: last update %s ago", s.name, age.Round(time.Second)), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"maps"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/host"
+)
+
+// hostCollector exports host attributes as an info metric, whose value is
+// always 1, so they can be joined onto other series in queries.
+type hostCollector struct {
+	info   *prometheus.GaugeVec
+	uptime prometheus.Gauge
+	// labels are the attributes currently reported, so the old series can
+	// be dropped if they change (for example after a rename or upgrade).
+	labels prometheus.Labels
+}
+
+func newHostCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	factory := promauto.With(reg)
+	return &hostCollector{
+		info: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "host_info",
+			Help: "Host attributes as labels; the value is always 1",
+		}, []string{"hostname", "os", "platform", "platform_version", "kernel_version"}),
+		uptime: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "host_uptime_seconds",
+			Help: "Time since the host booted in seconds",
+		}),
+	}, nil
+}
+
+func (c *hostCollector) Name() string { return "host" }
+
+func (c *hostCollector) Collect(ctx context.Context) error {
+	info, err := host.InfoWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	labels := prometheus.Labels{
+		"hostname":         info.Hostname,
+		"os":               info.OS,
+		"platform":         info.Platform,
+		"platform_version": info.PlatformVersion,
+		"kernel_version":   info.KernelVersion,
+	}
+	if c.labels != nil && !maps.Equal(labels, c.labels) {
+		c.info.Delete(c.labels)
+	}
+	c.labels = labels
+	c.info.With(labels).Set(1)
+	c.uptime.Set(float64(info.Uptime))
+	return nil
+}
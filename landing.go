@@ -0,0 +1,34 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+)
+
+var landingTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Observātor</title></head>
+<body>
+<h1>Observātor</h1>
+<p>Version {{.Version}}</p>
+<ul>
+<li><a href="{{.MetricsPath}}">Metrics</a></li>
+<li><a href="/healthz">Health</a></li>
+</ul>
+</body>
+</html>
+`))
+
+// landingHandler serves a small page at "/" pointing at the exporter's
+// endpoints, as other Prometheus exporters do. Any other unmatched path
+// still gets a 404.
+func landingHandler(metricsPath string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		landingTemplate.Execute(w, struct{ Version, MetricsPath string }{version, metricsPath})
+	})
+}
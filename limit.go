@@ -0,0 +1,130 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// seriesLimitRegisterer guards against label cardinality explosions. Every
+// metric registered through it, such as a GaugeVec, emits at most max
+// distinct label combinations; further ones are left out of scrapes.
+type seriesLimitRegisterer struct {
+	prometheus.Registerer
+	collector string
+	max       int
+	dropped   prometheus.Counter
+	warnings  *logLimiter
+}
+
+func newSeriesLimitRegisterer(reg prometheus.Registerer, collector string, limit int, dropped *prometheus.CounterVec) *seriesLimitRegisterer {
+	return &seriesLimitRegisterer{
+		Registerer: reg,
+		collector:  collector,
+		max:        limit,
+		dropped:    dropped.WithLabelValues(collector),
+		warnings:   newLogLimiter(warnPeriod),
+	}
+}
+
+func (r *seriesLimitRegisterer) Register(c prometheus.Collector) error {
+	return r.Registerer.Register(&seriesLimit{Collector: c, reg: r, admitted: map[string]bool{}, refused: map[string]bool{}})
+}
+
+func (r *seriesLimitRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// seriesLimit wraps a single registered metric. Label combinations are
+// admitted first come, first served, and keep their place for as long as
+// they are present, so the same series are reported from scrape to scrape.
+// Each combination is counted as dropped once, when it is first refused.
+type seriesLimit struct {
+	prometheus.Collector
+	reg *seriesLimitRegisterer
+
+	mu                sync.Mutex
+	admitted, refused map[string]bool
+}
+
+func (l *seriesLimit) Collect(ch chan<- prometheus.Metric) {
+	inner := make(chan prometheus.Metric)
+	go func() {
+		l.Collector.Collect(inner)
+		close(inner)
+	}()
+	var metrics []prometheus.Metric
+	var keys []string
+	present := map[string]bool{}
+	for m := range inner {
+		key := seriesKey(m)
+		metrics = append(metrics, m)
+		keys = append(keys, key)
+		if key != "" {
+			present[key] = true
+		}
+	}
+
+	l.mu.Lock()
+	admitted := map[string]bool{}
+	var candidates []string
+	for key := range present {
+		if l.admitted[key] {
+			admitted[key] = true
+		} else {
+			candidates = append(candidates, key)
+		}
+	}
+	// Sort newcomers so that which of them get in doesn't depend on the
+	// order the metric happened to emit them in.
+	slices.Sort(candidates)
+	refused := map[string]bool{}
+	var newlyRefused int
+	for _, key := range candidates {
+		if len(admitted) < l.reg.max {
+			admitted[key] = true
+			continue
+		}
+		refused[key] = true
+		if !l.refused[key] {
+			newlyRefused++
+		}
+	}
+	l.admitted, l.refused = admitted, refused
+	l.mu.Unlock()
+
+	if newlyRefused > 0 {
+		l.reg.dropped.Add(float64(newlyRefused))
+		l.reg.warnings.warn("Series limit reached, dropping series", "collector", l.reg.collector, "max_series", l.reg.max)
+	}
+	for i, m := range metrics {
+		if keys[i] == "" || admitted[keys[i]] {
+			ch <- m
+		}
+	}
+}
+
+// seriesKey identifies m's label combination, or is empty for a metric
+// without labels, which is never limited.
+func seriesKey(m prometheus.Metric) string {
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		// Let the registry report the broken metric.
+		return ""
+	}
+	var b strings.Builder
+	for _, lp := range pb.GetLabel() {
+		b.WriteString(lp.GetName())
+		b.WriteByte('=')
+		b.WriteString(lp.GetValue())
+		b.WriteByte(0)
+	}
+	return b.String()
+}
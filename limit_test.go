@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestSeriesLimit(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MaxSeries = 2
+	registry := prometheus.NewRegistry()
+	dropped := newDroppedSeriesCounter(&cfg, registry)
+	reg := newSeriesLimitRegisterer(registry, "test", cfg.MaxSeries, dropped)
+	gauge := promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "test_value",
+		Help: "Test gauge",
+	}, []string{"device"})
+	for _, device := range []string{"c", "a", "b"} {
+		gauge.WithLabelValues(device).Set(1)
+	}
+
+	// gathered returns the devices reported for test_value.
+	gathered := func() []string {
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var devices []string
+		for _, mf := range families {
+			if mf.GetName() != "test_value" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				devices = append(devices, m.GetLabel()[0].GetValue())
+			}
+		}
+		return devices
+	}
+
+	steps := []struct {
+		name        string
+		change      func()
+		wantDevices []string
+		wantDropped float64
+	}{
+		{"over the limit", func() {}, []string{"a", "b"}, 1},
+		{"refused again", func() {}, []string{"a", "b"}, 1},
+		{"room freed", func() { gauge.DeleteLabelValues("a") }, []string{"b", "c"}, 1},
+	}
+	for _, step := range steps {
+		step.change()
+		if got := gathered(); !slices.Equal(got, step.wantDevices) {
+			t.Errorf("%s: gathered %v, want %v", step.name, got, step.wantDevices)
+		}
+		var m dto.Metric
+		if err := dropped.WithLabelValues("test").Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.GetCounter().GetValue(); got != step.wantDropped {
+			t.Errorf("%s: collector_dropped_series_total = %v, want %v", step.name, got, step.wantDropped)
+		}
+	}
+}
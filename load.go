@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/load"
+)
+
+type loadCollector struct {
+	load1, load5, load15 prometheus.Gauge
+}
+
+func newLoadCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	// gopsutil approximates load average on Windows from the processor queue
+	// length, which isn't comparable to the Unix figure.
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("%w: load average is not available on %s", errUnsupported, runtime.GOOS)
+	}
+	err := probe(cfg, "load", func(ctx context.Context) error {
+		_, err := load.AvgWithContext(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnsupported, err)
+	}
+	factory := promauto.With(reg)
+	return &loadCollector{
+		load1: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "load_average_1m",
+			Help: "System load average over the last minute",
+		}),
+		load5: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "load_average_5m",
+			Help: "System load average over the last 5 minutes",
+		}),
+		load15: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "load_average_15m",
+			Help: "System load average over the last 15 minutes",
+		}),
+	}, nil
+}
+
+func (c *loadCollector) Name() string { return "load" }
+
+func (c *loadCollector) Collect(ctx context.Context) error {
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	c.load1.Set(avg.Load1)
+	c.load5.Set(avg.Load5)
+	c.load15.Set(avg.Load15)
+	return nil
+}
@@ -1,39 +1,325 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/shirou/gopsutil/cpu"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 )
 
-var cpuUsage = promauto.NewGauge(prometheus.GaugeOpts{
-	Name: "cpu_usage_percent",
-	Help: "Current CPU usage in percent",
-})
+// shutdownTimeout bounds how long in-flight scrapes may take to finish once
+// a termination signal has been received.
+const shutdownTimeout = 5 * time.Second
 
-func collectCPUUsage() {
-	for {
-		percent, err := cpu.Percent(time.Second, false)
-		if err != nil {
-			log.Println("Error getting CPU usage:", err)
-			return
+// newHandler returns the handler for all of the exporter's HTTP endpoints,
+// serving the metrics gathered from g and instrumenting itself through reg.
+func newHandler(cfg *config, g prometheus.Gatherer, reg prometheus.Registerer, statuses func() []*collectorStatus) (http.Handler, error) {
+	ereg := &errorRegisterer{Registerer: reg}
+	reg = ereg
+	var metricsHandler http.Handler = promhttp.InstrumentMetricHandler(reg, promhttp.HandlerFor(g, promhttp.HandlerOpts{
+		ErrorLog: slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+	}))
+	if cfg.AuthToken != "" {
+		metricsHandler = requireBearerToken(cfg.AuthToken, metricsHandler)
+	}
+	factory := promauto.With(reg)
+	metricsHandler = promhttp.InstrumentHandlerDuration(
+		factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "observator_http_request_duration_seconds",
+			Help:    "Time taken to serve metrics scrapes in seconds",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"code", "method"}),
+		promhttp.InstrumentHandlerCounter(
+			factory.NewCounterVec(prometheus.CounterOpts{
+				Name: "observator_http_requests_total",
+				Help: "Total number of metrics scrapes by HTTP status code and method",
+			}, []string{"code", "method"}),
+			metricsHandler,
+		),
+	)
+	mux := http.NewServeMux()
+	mux.Handle(cfg.MetricsPath, metricsHandler)
+	mux.Handle("/healthz", healthHandler(statuses))
+	if cfg.EnableSnapshot {
+		var snapshot http.Handler = snapshotHandler(g)
+		if cfg.AuthToken != "" {
+			snapshot = requireBearerToken(cfg.AuthToken, snapshot)
 		}
-		cpuUsage.Set(percent[0])
-		time.Sleep(time.Second)
+		mux.Handle("/snapshot", snapshot)
 	}
+	mux.Handle("/", landingHandler(cfg.MetricsPath))
+	if ereg.err != nil {
+		return nil, fmt.Errorf("registering HTTP metrics: %w", ereg.err)
+	}
+	return mux, nil
 }
 
 func main() {
-	go collectCPUUsage()
+	if handled, err := serviceCommand(os.Args[1:]); handled {
+		if err != nil {
+			slog.Error("Service command failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := parseConfig(os.Args[1:])
+	if err != nil {
+		slog.Error("Invalid configuration", "err", err)
+		os.Exit(1)
+	}
+	logger, err := newLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		slog.Error("Invalid configuration", "err", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	if cfg.Once {
+		if err := runOnce(context.Background(), cfg, os.Stdout); err != nil {
+			slog.Error("Collection failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if isService, err := runService(cfg); isService {
+		if err != nil {
+			slog.Error("Service failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Once shutdown has begun, a second signal kills the process as usual.
+	context.AfterFunc(ctx, stop)
+
+	if err := run(ctx, cfg, watchReloads(ctx, cfg)); err != nil {
+		slog.Error("Exporter failed", "err", err)
+		os.Exit(1)
+	}
+	slog.Info("Stopped")
+}
+
+// watchReloads re-reads the configuration on every SIGHUP while ctx is live,
+// if it came from a file, and sends the result on the returned channel. A
+// configuration that doesn't parse or validate is reported and skipped, so
+// the running one stays in effect.
+func watchReloads(ctx context.Context, cfg *config) <-chan *config {
+	if cfg.ConfigPath == "" {
+		return nil
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	reloads := make(chan *config)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-hup:
+			case <-ctx.Done():
+				return
+			}
+			slog.Info("Reloading configuration", "path", cfg.ConfigPath)
+			next, err := parseConfig(os.Args[1:])
+			if err != nil {
+				slog.Error("Invalid configuration, keeping the current one", "err", err)
+				continue
+			}
+			select {
+			case reloads <- next:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return reloads
+}
+
+// newRegistry returns the registry metrics are gathered from, and the
+// registerer to register them through, which adds the static labels. The
+// exporter's own build and runtime metrics are already registered.
+func newRegistry(cfg *config) (*prometheus.Registry, prometheus.Registerer, error) {
+	registry := prometheus.NewRegistry()
+	reg := prometheus.WrapRegistererWith(cfg.Labels, registry)
+	ereg := &errorRegisterer{Registerer: reg}
+	registerBuildInfo(ereg)
+	if err := registerRuntimeCollectors(ereg); err != nil {
+		return nil, nil, fmt.Errorf("registering runtime collectors: %w", err)
+	}
+	if ereg.err != nil {
+		return nil, nil, fmt.Errorf("registering exporter metrics: %w", ereg.err)
+	}
+	return registry, reg, nil
+}
+
+// errorRegisterer keeps the first registration error for the caller to
+// check instead of returning it, so that promauto and promhttp, which panic
+// on one, can register through it too. Short of a bug, registration only
+// fails when a static label has the same name as one of a metric's own
+// labels, so the error points at the -label names. Registering the same
+// metric twice is a bug, and still fails as usual.
+type errorRegisterer struct {
+	prometheus.Registerer
+	err error
+}
+
+func (r *errorRegisterer) Register(c prometheus.Collector) error {
+	err := r.Registerer.Register(c)
+	if err == nil || errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		return err
+	}
+	if r.err == nil {
+		r.err = fmt.Errorf("%w (check the -label names)", err)
+	}
+	return nil
+}
+
+func (r *errorRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
 
-	http.Handle("/metrics", promhttp.Handler())
-	fmt.Println("Starting server on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+// listen opens the listener for addr, which is either a TCP host:port or a
+// Unix domain socket path prefixed with "unix:". The socket file is removed
+// again when the listener is closed on shutdown; one left behind by an
+// unclean exit is replaced, unless something is still serving on it.
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, "unix:")
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("listen unix %s: socket is in use", path)
+		}
+		os.Remove(path)
+	}
+	return net.Listen("unix", path)
 }
 
+// run serves metrics and runs the collectors until ctx is cancelled, then
+// shuts everything down. It returns an error if the exporter can't be set up
+// or the server stops on its own. Configurations received from reloads are
+// applied as far as they can be without a restart; reloads may be nil.
+func run(ctx context.Context, cfg *config, reloads <-chan *config) error {
+	registry, reg, err := newRegistry(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Deferred in this order so that on any return the collectors are
+	// cancelled first and then waited for.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	collectors, err := newCollectorManager(ctx, cfg, reg)
+	if err != nil {
+		return err
+	}
+	collectors.startAll()
+	defer func() {
+		cancel()
+		collectors.wait()
+	}()
+	metrics := collectors.metrics
+
+	if cfg.PushgatewayURL != "" {
+		pusher := newPushCollector(cfg, registry)
+		// The pusher is deliberately left out of the health check: an
+		// unreachable Pushgateway doesn't make this process unhealthy.
+		status := newCollectorStatus(pusher.Name(), cfg.Interval)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runCollector(ctx, pusher, cfg.Interval, status, metrics)
+		}()
+	}
+
+	var meterProvider *sdkmetric.MeterProvider
+	if cfg.OTLPEndpoint != "" {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+			metrics.errors.WithLabelValues("otlp").Inc()
+			slog.Warn("OTLP export failed", "err", err)
+		}))
+		if meterProvider, err = newOTLPProvider(ctx, cfg, registry); err != nil {
+			return fmt.Errorf("setting up OTLP exporter: %w", err)
+		}
+	}
+
+	handler, err := newHandler(cfg, registry, reg, collectors.statuses)
+	if err != nil {
+		return err
+	}
+
+	ln, err := listen(cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Handler:  handler,
+		ErrorLog: slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	errc := make(chan error, 1)
+	if cfg.TLSCert != "" {
+		server.TLSConfig = &tls.Config{MinVersion: tlsVersions[cfg.TLSMinVersion]}
+		go func() {
+			errc <- server.ServeTLS(ln, cfg.TLSCert, cfg.TLSKey)
+		}()
+	} else {
+		go func() {
+			errc <- server.Serve(ln)
+		}()
+	}
+	slog.Info("Starting server", "version", version, "addr", cfg.ListenAddr, "tls", cfg.TLSCert != "", "collectors", len(collectors.names()))
+
+	for ctx.Err() == nil {
+		select {
+		case err := <-errc:
+			return err
+		case next := <-reloads:
+			cfg = collectors.reload(cfg, next)
+			slog.Info("Reloaded configuration", "collectors", len(collectors.names()))
+		case <-ctx.Done():
+		}
+	}
+	slog.Info("Shutting down")
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Server shutdown failed", "err", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Server failed", "err", err)
+	}
+	if meterProvider != nil {
+		// Shutdown exports one last time before stopping.
+		if err := meterProvider.Shutdown(shutdownCtx); err != nil {
+			slog.Error("OTLP exporter shutdown failed", "err", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestMetricsEndpointServesCPUUsage(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Collectors = []string{"cpu"}
+	registry := prometheus.NewRegistry()
+	ctx := context.Background()
+	m, err := newCollectorManager(ctx, &cfg, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collectors := m.collectors()
+	if len(collectors) != 1 {
+		t.Fatalf("got %d collectors, want 1", len(collectors))
+	}
+
+	// The first sample only primes the CPU collector; the second one is
+	// published.
+	if err := collectors[0].collector.Collect(ctx); !errors.Is(err, errBaseline) {
+		t.Fatalf("first Collect: got %v, want %v", err, errBaseline)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := collectors[0].collector.Collect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := newHandler(&cfg, registry, registry, func() []*collectorStatus { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics: status %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf, ok := families["cpu_usage_percent"]
+	if !ok {
+		t.Fatal("cpu_usage_percent missing from /metrics")
+	}
+	if got := mf.GetMetric()[0].GetGauge().GetValue(); got < 0 || got > 100 {
+		t.Errorf("cpu_usage_percent = %v, want a value in [0, 100]", got)
+	}
+}
+
+func TestCPUCollectorDoesNotBlock(t *testing.T) {
+	cfg := defaultConfig()
+	c, err := newCPUCollector(&cfg, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Collect must not wait out a measurement window of its own, or a
+	// shutdown would have to wait for it too.
+	ctx := context.Background()
+	for i, want := range []error{errBaseline, nil} {
+		start := time.Now()
+		if err := c.Collect(ctx); !errors.Is(err, want) {
+			t.Fatalf("Collect %d: got %v, want %v", i+1, err, want)
+		}
+		if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+			t.Errorf("Collect %d took %v, want well under the interval", i+1, elapsed)
+		}
+	}
+}
+
+func TestStaticLabelClashIsAnError(t *testing.T) {
+	for _, name := range []string{"version", "core"} {
+		cfg := defaultConfig()
+		cfg.Collectors = []string{"cpu"}
+		cfg.Labels = map[string]string{name: "x"}
+		_, reg, err := newRegistry(&cfg)
+		if err == nil {
+			_, err = newCollectorManager(context.Background(), &cfg, reg)
+		}
+		if err == nil {
+			t.Errorf("-label %s=x: got no error", name)
+		}
+	}
+}
+
+func TestProbeTimesOut(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Interval = 50 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	err := probe(&cfg, "stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("probe took %v with a %v interval", elapsed, cfg.Interval)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectorManager runs the enabled collectors, each in its own goroutine,
+// and on a configuration reload starts, stops and restarts them so that the
+// collector set and their intervals match the new configuration.
+type collectorManager struct {
+	ctx     context.Context
+	reg     prometheus.Registerer
+	metrics *collectorMetrics
+	dropped *prometheus.CounterVec
+	wg      sync.WaitGroup
+
+	// mu guards running, which the health check reads concurrently.
+	mu      sync.Mutex
+	running map[string]*runningCollector
+	// skipped holds the enabled collectors whose factory skipped them. The
+	// settings that decide that need a restart to change, so reload leaves
+	// them be unless they are disabled and enabled again.
+	skipped map[string]bool
+}
+
+type runningCollector struct {
+	collector Collector
+	// mu serializes calls to collector.Collect. It outlives any one runner,
+	// as a call that timed out may still be running when the collector is
+	// restarted with a new interval.
+	mu sync.Mutex
+	// reg records the metrics the collector registered, so they can be
+	// unregistered again when it is disabled.
+	reg      *trackingRegisterer
+	interval time.Duration
+	status   *collectorStatus
+	stop     context.CancelFunc
+	done     chan struct{}
+}
+
+// newCollectorManager creates the collectors enabled in cfg without starting
+// them. Once mode samples them directly; startAll runs them until ctx is
+// cancelled.
+func newCollectorManager(ctx context.Context, cfg *config, reg prometheus.Registerer) (*collectorManager, error) {
+	ereg := &errorRegisterer{Registerer: reg}
+	m := &collectorManager{
+		ctx:     ctx,
+		reg:     reg,
+		metrics: newCollectorMetrics(ereg),
+		dropped: newDroppedSeriesCounter(cfg, ereg),
+		running: map[string]*runningCollector{},
+		skipped: map[string]bool{},
+	}
+	if ereg.err != nil {
+		return nil, fmt.Errorf("registering collector metrics: %w", ereg.err)
+	}
+	for _, name := range enabledCollectors(cfg) {
+		rc, err := m.create(cfg, name)
+		if err != nil {
+			for _, rc := range m.running {
+				if c, ok := rc.collector.(closer); ok {
+					c.close()
+				}
+			}
+			return nil, err
+		}
+		if rc == nil {
+			m.skipped[name] = true
+			continue
+		}
+		m.running[name] = rc
+	}
+	return m, nil
+}
+
+// enabledCollectors returns the names of the collectors cfg enables, in
+// collectorNames order.
+func enabledCollectors(cfg *config) []string {
+	var names []string
+	for _, name := range collectorNames() {
+		if slices.Contains(cfg.Collectors, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// create builds the named collector, or returns nil if it is skipped.
+func (m *collectorManager) create(cfg *config, name string) (*runningCollector, error) {
+	reg := &trackingRegisterer{Registerer: m.reg}
+	c, err := newCollector(cfg, name, reg, m.dropped)
+	if err != nil || c == nil {
+		// A factory may have registered metrics before giving up.
+		reg.unregisterAll()
+		return nil, err
+	}
+	return &runningCollector{collector: c, reg: reg, interval: cfg.collectorInterval(name)}, nil
+}
+
+// collectors returns the collectors, sorted by name.
+func (m *collectorManager) collectors() []*runningCollector {
+	names := m.names()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	collectors := make([]*runningCollector, len(names))
+	for i, name := range names {
+		collectors[i] = m.running[name]
+	}
+	return collectors
+}
+
+// startAll starts every collector created by newCollectorManager.
+func (m *collectorManager) startAll() {
+	for _, rc := range m.collectors() {
+		m.start(rc, rc.interval)
+	}
+}
+
+// add creates the named collector and starts it, unless it is skipped.
+func (m *collectorManager) add(cfg *config, name string) error {
+	rc, err := m.create(cfg, name)
+	if err != nil {
+		return err
+	}
+	if rc == nil {
+		m.skipped[name] = true
+		return nil
+	}
+	slog.Info("Starting collector", "collector", name)
+	m.start(rc, rc.interval)
+	return nil
+}
+
+func (m *collectorManager) start(rc *runningCollector, interval time.Duration) {
+	ctx, stop := context.WithCancel(m.ctx)
+	rc.interval = interval
+	rc.status = newCollectorStatus(rc.collector.Name(), interval)
+	rc.stop = stop
+	rc.done = make(chan struct{})
+	m.mu.Lock()
+	m.running[rc.collector.Name()] = rc
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer close(rc.done)
+		runCollector(ctx, &serialCollector{rc.collector, &rc.mu}, interval, rc.status, m.metrics)
+	}()
+}
+
+// halt stops the named collector's goroutine and waits for it to return.
+func (m *collectorManager) halt(name string) *runningCollector {
+	m.mu.Lock()
+	rc := m.running[name]
+	delete(m.running, name)
+	m.mu.Unlock()
+	rc.stop()
+	<-rc.done
+	return rc
+}
+
+// remove stops the named collector, releases what it holds and drops all of
+// its series.
+func (m *collectorManager) remove(name string) {
+	rc := m.halt(name)
+	if c, ok := rc.collector.(closer); ok {
+		c.close()
+	}
+	rc.reg.unregisterAll()
+	m.metrics.errors.DeleteLabelValues(name)
+	m.metrics.lastSuccess.DeleteLabelValues(name)
+}
+
+func (m *collectorManager) names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.running))
+	for name := range m.running {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// statuses returns the status of every running collector, for the health
+// check.
+func (m *collectorManager) statuses() []*collectorStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]*collectorStatus, 0, len(m.running))
+	for _, rc := range m.running {
+		statuses = append(statuses, rc.status)
+	}
+	slices.SortFunc(statuses, func(a, b *collectorStatus) int { return strings.Compare(a.name, b.name) })
+	return statuses
+}
+
+// wait blocks until every collector goroutine has returned, which happens
+// once the context the manager was created with is cancelled.
+func (m *collectorManager) wait() {
+	m.wg.Wait()
+}
+
+// reload applies the live-changeable settings of next, the collector set
+// and intervals, to the running configuration cur, and returns the result.
+// Collectors keep their state across an interval change; a collector that
+// is enabled again starts afresh. Other settings, and the interval the
+// Pushgateway and OTLP exporters push at, are left as they were, with a
+// warning that they need a restart.
+func (m *collectorManager) reload(cur, next *config) *config {
+	if changed := restartRequired(cur, next); len(changed) > 0 {
+		slog.Warn("Configuration changes require a restart to take effect", "settings", changed)
+	}
+	applied := *cur
+	applied.Interval = next.Interval
+	applied.Intervals = next.Intervals
+	applied.Collectors = next.Collectors
+
+	for _, name := range m.names() {
+		if !slices.Contains(applied.Collectors, name) {
+			slog.Info("Stopping collector", "collector", name)
+			m.remove(name)
+		}
+	}
+	for name := range m.skipped {
+		if !slices.Contains(applied.Collectors, name) {
+			delete(m.skipped, name)
+		}
+	}
+	for _, name := range enabledCollectors(&applied) {
+		if m.skipped[name] {
+			continue
+		}
+		m.mu.Lock()
+		rc, ok := m.running[name]
+		m.mu.Unlock()
+		if !ok {
+			if err := m.add(&applied, name); err != nil {
+				slog.Error("Starting collector failed", "collector", name, "err", err)
+			}
+			continue
+		}
+		if interval := applied.collectorInterval(name); interval != rc.interval {
+			slog.Info("Changing collector interval", "collector", name, "interval", interval)
+			m.start(m.halt(name), interval)
+		}
+	}
+	return &applied
+}
+
+// liveSettings are the config fields reload applies.
+var liveSettings = []string{"Interval", "Intervals", "Collectors"}
+
+// restartRequired lists the settings, by their configuration file keys, that
+// differ between cur and next but can't be changed without a restart.
+func restartRequired(cur, next *config) []string {
+	var changed []string
+	if cur.Interval != next.Interval && (cur.PushgatewayURL != "" || cur.OTLPEndpoint != "") {
+		changed = append(changed, "interval (for pushgateway_url and otlp_endpoint)")
+	}
+	cv, nv := reflect.ValueOf(cur).Elem(), reflect.ValueOf(next).Elem()
+	for i := range cv.NumField() {
+		f := cv.Type().Field(i)
+		key := f.Tag.Get("yaml")
+		if key == "-" || slices.Contains(liveSettings, f.Name) {
+			continue
+		}
+		if !reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// serialCollector holds mu for the duration of every Collect call.
+type serialCollector struct {
+	Collector
+	mu *sync.Mutex
+}
+
+func (c *serialCollector) Collect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Collector.Collect(ctx)
+}
+
+// trackingRegisterer remembers what was registered through it.
+type trackingRegisterer struct {
+	prometheus.Registerer
+	registered []prometheus.Collector
+}
+
+func (r *trackingRegisterer) Register(c prometheus.Collector) error {
+	if err := r.Registerer.Register(c); err != nil {
+		return err
+	}
+	r.registered = append(r.registered, c)
+	return nil
+}
+
+func (r *trackingRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (r *trackingRegisterer) unregisterAll() {
+	for _, c := range r.registered {
+		r.Registerer.Unregister(c)
+	}
+	r.registered = nil
+}
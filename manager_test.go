@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// stuckCollector ignores its context and blocks in Collect until released,
+// like a stat on a hung NFS mount.
+type stuckCollector struct {
+	release           chan struct{}
+	active, maxActive atomic.Int32
+}
+
+func (c *stuckCollector) Name() string { return "stuck" }
+
+func (c *stuckCollector) Collect(ctx context.Context) error {
+	n := c.active.Add(1)
+	defer c.active.Add(-1)
+	for {
+		m := c.maxActive.Load()
+		if n <= m || c.maxActive.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	<-c.release
+	return nil
+}
+
+// withTestCollector makes c available as a collector for the duration of the
+// test.
+func withTestCollector(t *testing.T, c Collector) {
+	collectorRegistry[c.Name()] = func(cfg *config, reg prometheus.Registerer) (Collector, error) {
+		return c, nil
+	}
+	t.Cleanup(func() { delete(collectorRegistry, c.Name()) })
+}
+
+func TestReloadDoesNotOverlapStuckCollect(t *testing.T) {
+	c := &stuckCollector{release: make(chan struct{})}
+	withTestCollector(t, c)
+	cfg := defaultConfig()
+	cfg.Collectors = []string{c.Name()}
+	cfg.Interval = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m, err := newCollectorManager(ctx, &cfg, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.startAll()
+	time.Sleep(50 * time.Millisecond)
+
+	// Changing the interval restarts the runner while the first call is
+	// still stuck.
+	next := cfg
+	next.Interval = 30 * time.Millisecond
+	m.reload(&cfg, &next)
+	time.Sleep(100 * time.Millisecond)
+	close(c.release)
+
+	cancel()
+	m.wait()
+	if got := c.maxActive.Load(); got != 1 {
+		t.Errorf("got %d concurrent Collect calls, want 1", got)
+	}
+}
+
+// withGaugeCollector makes a collector available that registers a gauge
+// named after it and does nothing on Collect.
+func withGaugeCollector(t *testing.T, name string) {
+	collectorRegistry[name] = func(cfg *config, reg prometheus.Registerer) (Collector, error) {
+		promauto.With(reg).NewGauge(prometheus.GaugeOpts{Name: name + "_value", Help: "Test gauge"})
+		return idleCollector(name), nil
+	}
+	t.Cleanup(func() { delete(collectorRegistry, name) })
+}
+
+type idleCollector string
+
+func (c idleCollector) Name() string                      { return string(c) }
+func (c idleCollector) Collect(ctx context.Context) error { return nil }
+
+func TestReload(t *testing.T) {
+	withGaugeCollector(t, "test_a")
+	withGaugeCollector(t, "test_b")
+	withGaugeCollector(t, "test_c")
+	cfg := defaultConfig()
+	cfg.Collectors = []string{"test_a", "test_b"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := prometheus.NewRegistry()
+	m, err := newCollectorManager(ctx, &cfg, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.startAll()
+
+	next := cfg
+	next.Collectors = []string{"test_b", "test_c"}
+	next.Intervals = map[string]time.Duration{"test_b": time.Minute}
+	next.ListenAddr = ":1"
+	applied := m.reload(&cfg, &next)
+
+	if got, want := m.names(), []string{"test_b", "test_c"}; !slices.Equal(got, want) {
+		t.Errorf("running collectors: got %v, want %v", got, want)
+	}
+	if got := m.running["test_b"].interval; got != time.Minute {
+		t.Errorf("test_b interval: got %v, want %v", got, time.Minute)
+	}
+	if applied.ListenAddr != cfg.ListenAddr {
+		t.Errorf("listen_addr: got %q, want it unchanged at %q", applied.ListenAddr, cfg.ListenAddr)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gauges []string
+	for _, mf := range families {
+		if name := mf.GetName(); name != "collector_last_success_timestamp_seconds" && name != "collector_errors_total" {
+			gauges = append(gauges, name)
+		}
+	}
+	if want := []string{"test_b_value", "test_c_value"}; !slices.Equal(gauges, want) {
+		t.Errorf("registered metrics: got %v, want %v", gauges, want)
+	}
+
+	cancel()
+	m.wait()
+}
+
+func TestRestartRequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cur, next *config)
+		want   []string
+	}{
+		{"unchanged", func(cur, next *config) {}, nil},
+		{"live settings", func(cur, next *config) {
+			next.Interval = time.Minute
+			next.Intervals = map[string]time.Duration{"cpu": time.Second}
+			next.Collectors = []string{"cpu"}
+		}, nil},
+		{"listen address", func(cur, next *config) { next.ListenAddr = ":1" }, []string{"listen_addr"}},
+		{"labels", func(cur, next *config) { next.Labels = map[string]string{"env": "prod"} }, []string{"labels"}},
+		{"flag-only settings", func(cur, next *config) { next.Once, next.ConfigPath = true, "other.yaml" }, nil},
+		{"interval with pushgateway", func(cur, next *config) {
+			cur.PushgatewayURL, next.PushgatewayURL = "http://pushgateway:9091", "http://pushgateway:9091"
+			next.Interval = time.Minute
+		}, []string{"interval (for pushgateway_url and otlp_endpoint)"}},
+		{"interval with otlp", func(cur, next *config) {
+			cur.OTLPEndpoint, next.OTLPEndpoint = "http://collector:4318", "http://collector:4318"
+			next.Interval = time.Minute
+		}, []string{"interval (for pushgateway_url and otlp_endpoint)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur, next := defaultConfig(), defaultConfig()
+			tt.change(&cur, &next)
+			if got := restartRequired(&cur, &next); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadDoesNotRetrySkippedCollectors(t *testing.T) {
+	var calls int
+	collectorRegistry["test_skipped"] = func(cfg *config, reg prometheus.Registerer) (Collector, error) {
+		calls++
+		return nil, errNotConfigured
+	}
+	t.Cleanup(func() { delete(collectorRegistry, "test_skipped") })
+	cfg := defaultConfig()
+	cfg.Collectors = []string{"test_skipped"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m, err := newCollectorManager(ctx, &cfg, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.startAll()
+
+	next := cfg
+	for range 2 {
+		m.reload(&cfg, &next)
+	}
+	if calls != 1 {
+		t.Errorf("unchanged reloads: factory called %d times, want 1", calls)
+	}
+
+	// Disabling and enabling the collector again tries it afresh.
+	disabled := cfg
+	disabled.Collectors = nil
+	m.reload(&cfg, &disabled)
+	m.reload(&disabled, &next)
+	if calls != 2 {
+		t.Errorf("after re-enabling: factory called %d times, want 2", calls)
+	}
+}
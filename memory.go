@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/mem"
+)
+
+type memoryCollector struct {
+	total, used, available, usedPercent prometheus.Gauge
+	// cached, buffers and shared are only reported on Linux, the one
+	// platform where gopsutil fills them in; elsewhere they are nil.
+	cached, buffers, shared prometheus.Gauge
+}
+
+func newMemoryCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	factory := promauto.With(reg)
+	c := &memoryCollector{
+		total: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "memory_total_bytes",
+			Help: "Total physical memory in bytes",
+		}),
+		used: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "memory_used_bytes",
+			Help: "Used physical memory in bytes",
+		}),
+		available: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "memory_available_bytes",
+			Help: "Memory available for starting new applications in bytes",
+		}),
+		usedPercent: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "memory_used_percent",
+			Help: "Used physical memory in percent",
+		}),
+	}
+	if runtime.GOOS == "linux" {
+		c.cached = factory.NewGauge(prometheus.GaugeOpts{
+			Name: "memory_cached_bytes",
+			Help: "Memory used by the page cache in bytes",
+		})
+		c.buffers = factory.NewGauge(prometheus.GaugeOpts{
+			Name: "memory_buffers_bytes",
+			Help: "Memory used by kernel buffers in bytes",
+		})
+		c.shared = factory.NewGauge(prometheus.GaugeOpts{
+			Name: "memory_shared_bytes",
+			Help: "Memory used by shared memory and tmpfs in bytes",
+		})
+	}
+	return c, nil
+}
+
+func (c *memoryCollector) Name() string { return "memory" }
+
+func (c *memoryCollector) Collect(ctx context.Context) error {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	c.total.Set(float64(vm.Total))
+	c.used.Set(float64(vm.Used))
+	c.available.Set(float64(vm.Available))
+	c.usedPercent.Set(vm.UsedPercent)
+	if c.cached != nil {
+		c.cached.Set(float64(vm.Cached))
+		c.buffers.Set(float64(vm.Buffers))
+		c.shared.Set(float64(vm.Shared))
+	}
+	return nil
+}
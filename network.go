@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/net"
+)
+
+type networkCollector struct {
+	bytesSent, bytesReceived *prometheus.CounterVec
+	sent, received           *counterTracker
+	// errs and drops are labeled by direction as well, and their trackers
+	// keyed by interface and direction together.
+	errs, drops             *prometheus.CounterVec
+	errTracker, dropTracker *counterTracker
+	// known holds the interfaces reported on the previous sample, so series
+	// for interfaces that have since disappeared can be dropped.
+	known map[string]bool
+}
+
+func newNetworkCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	factory := promauto.With(reg)
+	return &networkCollector{
+		bytesSent: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "network_bytes_sent_total",
+			Help: "Total bytes sent per network interface",
+		}, []string{"interface"}),
+		bytesReceived: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "network_bytes_received_total",
+			Help: "Total bytes received per network interface",
+		}, []string{"interface"}),
+		errs: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "network_errors_total",
+			Help: "Total transmit and receive errors per network interface",
+		}, []string{"interface", "direction"}),
+		drops: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "network_dropped_total",
+			Help: "Total packets dropped on transmit and receive per network interface",
+		}, []string{"interface", "direction"}),
+		sent:        newCounterTracker(),
+		received:    newCounterTracker(),
+		errTracker:  newCounterTracker(),
+		dropTracker: newCounterTracker(),
+		known:       map[string]bool{},
+	}, nil
+}
+
+func (c *networkCollector) Name() string { return "network" }
+
+func (c *networkCollector) Collect(ctx context.Context) error {
+	counters, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, ioc := range counters {
+		seen[ioc.Name] = true
+		c.bytesSent.WithLabelValues(ioc.Name).Add(c.sent.delta(ioc.Name, ioc.BytesSent))
+		c.bytesReceived.WithLabelValues(ioc.Name).Add(c.received.delta(ioc.Name, ioc.BytesRecv))
+		for _, d := range []struct {
+			direction  string
+			errs, drop uint64
+		}{
+			{"in", ioc.Errin, ioc.Dropin},
+			{"out", ioc.Errout, ioc.Dropout},
+		} {
+			key := ioc.Name + "/" + d.direction
+			c.errs.WithLabelValues(ioc.Name, d.direction).Add(c.errTracker.delta(key, d.errs))
+			c.drops.WithLabelValues(ioc.Name, d.direction).Add(c.dropTracker.delta(key, d.drop))
+		}
+	}
+	for name := range c.known {
+		if !seen[name] {
+			c.bytesSent.DeleteLabelValues(name)
+			c.bytesReceived.DeleteLabelValues(name)
+			c.sent.forget(name)
+			c.received.forget(name)
+			for _, direction := range []string{"in", "out"} {
+				c.errs.DeleteLabelValues(name, direction)
+				c.drops.DeleteLabelValues(name, direction)
+				c.errTracker.forget(name + "/" + direction)
+				c.dropTracker.forget(name + "/" + direction)
+			}
+		}
+	}
+	c.known = seen
+	return nil
+}
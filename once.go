@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+// runOnce samples each collector a single time and writes the resulting
+// metrics to w in the Prometheus text format, for smoke tests and cron jobs.
+// Collectors that report rates take their baseline reading first and are
+// sampled one interval later, so that they too have a value to print. Each
+// call is bounded by the same timeout the collector's runner would apply.
+func runOnce(ctx context.Context, cfg *config, w io.Writer) error {
+	registry, reg, err := newRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	m, err := newCollectorManager(ctx, cfg, reg)
+	if err != nil {
+		return err
+	}
+	collectors := m.collectors()
+
+	var primed bool
+	for _, rc := range collectors {
+		if p, ok := rc.collector.(primer); ok {
+			_, err := collectWithTimeout(ctx, p.prime, collectTimeout(rc.interval))
+			if err != nil && !errors.Is(err, errBaseline) && !errors.Is(err, errUnsupported) {
+				return fmt.Errorf("%s collector: %w", rc.collector.Name(), err)
+			}
+			primed = true
+		}
+	}
+	if primed {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.Interval):
+		}
+	}
+
+	for _, rc := range collectors {
+		name := rc.collector.Name()
+		_, err := collectWithTimeout(ctx, rc.collector.Collect, collectTimeout(rc.interval))
+		if errors.Is(err, errUnsupported) {
+			slog.Warn("Skipping collector", "collector", name, "err", err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s collector: %w", name, err)
+		}
+		m.metrics.lastSuccess.WithLabelValues(name).SetToCurrentTime()
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		return err
+	}
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunOnceTimesOutStuckCollector(t *testing.T) {
+	c := &stuckCollector{release: make(chan struct{})}
+	defer close(c.release)
+	withTestCollector(t, c)
+	cfg := defaultConfig()
+	cfg.Collectors = []string{c.Name()}
+	cfg.Interval = 50 * time.Millisecond
+
+	start := time.Now()
+	err := runOnce(context.Background(), &cfg, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("runOnce took %v with a %v interval", elapsed, cfg.Interval)
+	}
+}
+
+func TestRunOnceStopsWaitingOnCancel(t *testing.T) {
+	cfg := defaultConfig()
+	// The CPU collector needs a baseline, so runOnce waits an interval.
+	cfg.Collectors = []string{"cpu"}
+	cfg.Interval = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := runOnce(ctx, &cfg, io.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
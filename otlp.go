@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// startTime stands in for the start of every cumulative counter exported
+// over OTLP, since that's when this process began counting them.
+var startTime = time.Now()
+
+// newOTLPProvider returns a meter provider that exports the metrics held in
+// g to cfg.OTLPEndpoint over OTLP/HTTP every interval. Nothing is measured
+// twice: the values are the ones the collectors already published.
+func newOTLPProvider(ctx context.Context, cfg *config, g prometheus.Gatherer) (*sdkmetric.MeterProvider, error) {
+	opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpointURL(cfg.OTLPEndpoint)}
+	if u, err := url.Parse(cfg.OTLPEndpoint); err == nil && (u.Path == "" || u.Path == "/") {
+		opts = append(opts, otlpmetrichttp.WithURLPath("/v1/metrics"))
+	}
+	exporter, err := otlpmetrichttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", "observator")))
+	if err != nil {
+		return nil, err
+	}
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(cfg.Interval),
+		sdkmetric.WithProducer(gathererProducer{g}),
+	)
+	return sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res)), nil
+}
+
+// gathererProducer translates the gauges and counters gathered from a
+// Prometheus registry into OTLP metric data. Histograms and summaries, which
+// only the client library's own runtime metrics use, are left out.
+type gathererProducer struct {
+	g prometheus.Gatherer
+}
+
+func (p gathererProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	families, err := p.g.Gather()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var metrics []metricdata.Metrics
+	for _, mf := range families {
+		m := metricdata.Metrics{Name: mf.GetName(), Description: mf.GetHelp()}
+		switch mf.GetType() {
+		case dto.MetricType_GAUGE:
+			m.Data = metricdata.Gauge[float64]{
+				DataPoints: dataPoints(mf, now, func(m *dto.Metric) float64 { return m.GetGauge().GetValue() }),
+			}
+		case dto.MetricType_COUNTER:
+			m.Data = metricdata.Sum[float64]{
+				DataPoints:  dataPoints(mf, now, func(m *dto.Metric) float64 { return m.GetCounter().GetValue() }),
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+			}
+		default:
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return []metricdata.ScopeMetrics{{
+		Scope:   instrumentation.Scope{Name: "github.com/arjfabian/observator"},
+		Metrics: metrics,
+	}}, nil
+}
+
+func dataPoints(mf *dto.MetricFamily, now time.Time, value func(*dto.Metric) float64) []metricdata.DataPoint[float64] {
+	points := make([]metricdata.DataPoint[float64], 0, len(mf.GetMetric()))
+	for _, m := range mf.GetMetric() {
+		attrs := make([]attribute.KeyValue, 0, len(m.GetLabel()))
+		for _, l := range m.GetLabel() {
+			attrs = append(attrs, attribute.String(l.GetName(), l.GetValue()))
+		}
+		points = append(points, metricdata.DataPoint[float64]{
+			Attributes: attribute.NewSet(attrs...),
+			StartTime:  startTime,
+			Time:       now,
+			Value:      value(m),
+		})
+	}
+	return points
+}
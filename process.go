@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/process"
+)
+
+// processCollector reports on a single process chosen either by PID or by
+// executable name. A process watched by name is looked up again after it
+// exits, so a restarted process is picked back up.
+type processCollector struct {
+	pid  int32
+	name string
+
+	// proc is the process currently being watched, or nil while there is
+	// none. primed is false until proc's CPU times have been read once, as
+	// the first CPU percentage has nothing to compare against.
+	proc   *process.Process
+	labels prometheus.Labels
+	primed bool
+
+	cpuPercent, rss, threads *prometheus.GaugeVec
+}
+
+func newProcessCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	if cfg.WatchPID == 0 && cfg.WatchName == "" {
+		return nil, errNotConfigured
+	}
+	c := &processCollector{pid: cfg.WatchPID, name: cfg.WatchName}
+	if c.pid != 0 {
+		p, err := process.NewProcess(c.pid)
+		if err != nil {
+			return nil, fmt.Errorf("process %d: %w", c.pid, err)
+		}
+		c.watch(p)
+	}
+
+	labels := []string{"pid", "name"}
+	factory := promauto.With(reg)
+	c.cpuPercent = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "process_cpu_percent",
+		Help: "CPU usage of the watched process in percent of one core",
+	}, labels)
+	c.rss = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "process_memory_rss_bytes",
+		Help: "Resident set size of the watched process in bytes",
+	}, labels)
+	c.threads = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "process_num_threads",
+		Help: "Number of threads of the watched process",
+	}, labels)
+	return c, nil
+}
+
+func (c *processCollector) Name() string { return "process" }
+
+// prime relies on Collect not publishing a CPU percentage until it has a
+// previous reading.
+func (c *processCollector) prime(ctx context.Context) error { return c.Collect(ctx) }
+
+func (c *processCollector) Collect(ctx context.Context) error {
+	if c.proc == nil {
+		if c.name == "" {
+			// A process watched by PID is gone for good; the PID may
+			// since have been reused by something else.
+			return nil
+		}
+		p, err := c.findByName(ctx)
+		if err != nil || p == nil {
+			return err
+		}
+		c.watch(p)
+	}
+
+	if running, err := c.proc.IsRunningWithContext(ctx); err == nil && !running {
+		c.lost()
+		return nil
+	}
+	cpuPercent, err := c.proc.PercentWithContext(ctx, 0)
+	if err != nil {
+		return c.check(err)
+	}
+	memory, err := c.proc.MemoryInfoWithContext(ctx)
+	if err != nil {
+		return c.check(err)
+	}
+	threads, err := c.proc.NumThreadsWithContext(ctx)
+	if err != nil {
+		return c.check(err)
+	}
+
+	if c.primed {
+		c.cpuPercent.With(c.labels).Set(cpuPercent)
+	}
+	c.primed = true
+	c.rss.With(c.labels).Set(float64(memory.RSS))
+	c.threads.With(c.labels).Set(float64(threads))
+	return nil
+}
+
+func (c *processCollector) findByName(ctx context.Context) (*process.Process, error) {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range procs {
+		if name, err := p.NameWithContext(ctx); err == nil && name == c.name {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *processCollector) watch(p *process.Process) {
+	name, _ := p.Name()
+	slog.Info("Watching process", "pid", p.Pid, "name", name)
+	c.proc = p
+	c.labels = prometheus.Labels{"pid": strconv.Itoa(int(p.Pid)), "name": name}
+	c.primed = false
+}
+
+// lost drops the series of a process that has exited so its last values
+// aren't reported as if it were still running.
+func (c *processCollector) lost() {
+	slog.Info("Watched process exited", "pid", c.labels["pid"], "name", c.labels["name"])
+	if c.cpuPercent != nil {
+		c.cpuPercent.Delete(c.labels)
+		c.rss.Delete(c.labels)
+		c.threads.Delete(c.labels)
+	}
+	c.proc = nil
+}
+
+// check treats the process disappearing between calls as an exit rather
+// than a collection error.
+func (c *processCollector) check(err error) error {
+	if errors.Is(err, process.ErrorProcessNotRunning) {
+		c.lost()
+		return nil
+	}
+	return err
+}
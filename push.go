@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// pushCollector pushes everything gathered from the registry to a
+// Pushgateway. Running it like a collector means failed pushes are retried
+// on the next tick and counted in collector_errors_total.
+type pushCollector struct {
+	pusher *push.Pusher
+}
+
+func newPushCollector(cfg *config, g prometheus.Gatherer) *pushCollector {
+	pusher := push.New(cfg.PushgatewayURL, cfg.PushJob).Gatherer(g)
+	for name, value := range cfg.PushLabels {
+		pusher = pusher.Grouping(name, value)
+	}
+	return &pushCollector{pusher: pusher}
+}
+
+func (c *pushCollector) Name() string { return "push" }
+
+func (c *pushCollector) Collect(ctx context.Context) error {
+	return c.pusher.PushContext(ctx)
+}
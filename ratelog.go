@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// warnPeriod is how often a recurring collection warning is repeated.
+const warnPeriod = time.Minute
+
+// logLimiter keeps a persistent failure from flooding the log. The first
+// occurrence of a warning is logged straight away; identical ones (same
+// message and attributes) within the following period are only counted, and
+// the count is reported as "suppressed" when the warning is next logged.
+// collector_errors_total remains the place to look for the real error rate.
+type logLimiter struct {
+	period time.Duration
+
+	mu   sync.Mutex
+	seen map[string]*limitedWarning
+}
+
+type limitedWarning struct {
+	logged     time.Time
+	suppressed int
+}
+
+func newLogLimiter(period time.Duration) *logLimiter {
+	return &logLimiter{period: period, seen: map[string]*limitedWarning{}}
+}
+
+// warn logs msg with args at warning level, subject to rate limiting.
+func (l *logLimiter) warn(msg string, args ...any) {
+	key := msg + "\x00" + fmt.Sprint(args...)
+	now := time.Now()
+
+	l.mu.Lock()
+	w, ok := l.seen[key]
+	if ok && now.Sub(w.logged) < l.period {
+		w.suppressed++
+		l.mu.Unlock()
+		return
+	}
+	var suppressed int
+	if ok {
+		suppressed = w.suppressed
+	}
+	// Forget warnings that have stopped recurring, so that errors whose
+	// text varies don't accumulate here forever.
+	for k, w := range l.seen {
+		if w.suppressed == 0 && now.Sub(w.logged) >= l.period {
+			delete(l.seen, k)
+		}
+	}
+	l.seen[key] = &limitedWarning{logged: now}
+	l.mu.Unlock()
+
+	if suppressed > 0 {
+		args = append(args, "suppressed", suppressed)
+	}
+	slog.Warn(msg, args...)
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// registerRuntimeCollectors registers the Go runtime collector and the
+// process collector for this exporter through reg. On Linux the process
+// collector reads /proc/self, which provides process_open_fds and
+// process_max_fds alongside CPU time and memory, so fd leaks in the
+// collectors show up.
+func registerRuntimeCollectors(reg prometheus.Registerer) error {
+	return errors.Join(
+		reg.Register(collectors.NewGoCollector()),
+		reg.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})),
+	)
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+// serviceCommand handles the service management subcommands, which only
+// exist on Windows.
+func serviceCommand(args []string) (bool, error) {
+	return false, nil
+}
+
+// runService runs the exporter as a system service where the platform has
+// such a notion separate from a plain process; elsewhere it does nothing.
+func runService(cfg *config) (bool, error) {
+	return false, nil
+}
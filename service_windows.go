@@ -0,0 +1,210 @@
+//go:build windows
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const serviceName = "observator"
+
+// serviceCommand handles "install" and "uninstall". Any flags following
+// "install" are stored in the service's command line, so
+//
+//	observator install -listen-addr :9100 -collectors cpu,memory
+//
+// registers a service that starts with those flags.
+func serviceCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	switch args[0] {
+	case "install":
+		return true, installService(args[1:])
+	case "uninstall":
+		return true, uninstallService()
+	}
+	return false, nil
+}
+
+func installService(args []string) error {
+	// Check the flags now rather than having the service fail on start.
+	if _, err := parseConfig(args); err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.Abs(exe); err != nil {
+		return err
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "Observātor",
+		Description: "Exports host metrics for Prometheus",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		s.Delete()
+		return fmt.Errorf("registering event log source: %w", err)
+	}
+	slog.Info("Service installed", "name", serviceName, "path", exe)
+	return nil
+}
+
+func uninstallService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", serviceName, err)
+	}
+	defer s.Close()
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	if err := eventlog.Remove(serviceName); err != nil {
+		return fmt.Errorf("removing event log source: %w", err)
+	}
+	slog.Info("Service uninstalled", "name", serviceName)
+	return nil
+}
+
+// runService runs the exporter under the Service Control Manager when the
+// process was started by it, logging to the Windows event log. Started
+// from a console, it returns false and the exporter runs as usual.
+func runService(cfg *config) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, err
+	}
+	elog, err := eventlog.Open(serviceName)
+	if err != nil {
+		return true, err
+	}
+	defer elog.Close()
+	level, err := parseLogLevel(cfg.LogLevel)
+	if err != nil {
+		return true, err
+	}
+	slog.SetDefault(slog.New(newEventLogHandler(elog, level)))
+
+	return true, svc.Run(serviceName, &service{cfg: cfg})
+}
+
+type service struct {
+	cfg *config
+}
+
+// Execute runs the exporter until the SCM asks it to stop or shut down,
+// which cancels its context the same way a signal does on the console.
+func (s *service) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- run(ctx, s.cfg, nil)
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case err := <-errc:
+			if err != nil {
+				slog.Error("Exporter failed", "err", err)
+				return false, 1
+			}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				if err := <-errc; err != nil {
+					slog.Error("Exporter failed", "err", err)
+					return false, 1
+				}
+				slog.Info("Stopped")
+				return false, 0
+			}
+		}
+	}
+}
+
+// eventLogHandler writes each record to the Windows event log at the
+// matching severity, formatted as a text log line.
+type eventLogHandler struct {
+	elog *eventlog.Log
+	// text formats records into buf; mu guards buf, which is shared by all
+	// handlers derived through WithAttrs and WithGroup.
+	text slog.Handler
+	mu   *sync.Mutex
+	buf  *bytes.Buffer
+}
+
+func newEventLogHandler(elog *eventlog.Log, level slog.Level) *eventLogHandler {
+	buf := &bytes.Buffer{}
+	return &eventLogHandler{
+		elog: elog,
+		text: slog.NewTextHandler(buf, &slog.HandlerOptions{Level: level}),
+		mu:   &sync.Mutex{},
+		buf:  buf,
+	}
+}
+
+func (h *eventLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.text.Enabled(ctx, level)
+}
+
+func (h *eventLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buf.Reset()
+	if err := h.text.Handle(ctx, r); err != nil {
+		return err
+	}
+	msg := string(bytes.TrimSpace(h.buf.Bytes()))
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.elog.Error(1, msg)
+	case r.Level >= slog.LevelWarn:
+		return h.elog.Warning(1, msg)
+	default:
+		return h.elog.Info(1, msg)
+	}
+}
+
+func (h *eventLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &eventLogHandler{elog: h.elog, text: h.text.WithAttrs(attrs), mu: h.mu, buf: h.buf}
+}
+
+func (h *eventLogHandler) WithGroup(name string) slog.Handler {
+	return &eventLogHandler{elog: h.elog, text: h.text.WithGroup(name), mu: h.mu, buf: h.buf}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// snapshotSample is one labeled value of a metric in a snapshot.
+type snapshotSample struct {
+	Labels map[string]string `json:"labels"`
+	Value  snapshotValue     `json:"value"`
+}
+
+// snapshotValue is a metric value that encodes NaN and infinities, which
+// JSON can't represent, as null.
+type snapshotValue float64
+
+func (v snapshotValue) MarshalJSON() ([]byte, error) {
+	f := float64(v)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f)
+}
+
+// snapshotHandler serves the current values gathered from g as a JSON
+// object keyed by metric name. A metric with a single unlabeled value maps
+// straight to that number; any other maps to a list of labeled samples.
+// Histograms and summaries are left out, since they have no single value.
+//
+// Nothing is re-measured: this reads what the collectors last recorded,
+// exactly as a scrape of /metrics would.
+func snapshotHandler(g prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		families, err := g.Gather()
+		if err != nil && len(families) == 0 {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			// As with promhttp's default, serve whatever could be gathered.
+			slog.Warn("Gathering snapshot failed", "err", err)
+		}
+
+		snapshot := make(map[string]any, len(families))
+		for _, mf := range families {
+			var samples []snapshotSample
+			for _, m := range mf.GetMetric() {
+				value, ok := scalarValue(mf.GetType(), m)
+				if !ok {
+					continue
+				}
+				labels := make(map[string]string, len(m.GetLabel()))
+				for _, lp := range m.GetLabel() {
+					labels[lp.GetName()] = lp.GetValue()
+				}
+				samples = append(samples, snapshotSample{Labels: labels, Value: snapshotValue(value)})
+			}
+			switch {
+			case len(samples) == 1 && len(samples[0].Labels) == 0:
+				snapshot[mf.GetName()] = samples[0].Value
+			case len(samples) > 0:
+				snapshot[mf.GetName()] = samples
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(snapshot); err != nil {
+			slog.Warn("Writing snapshot failed", "err", err)
+		}
+	})
+}
+
+func scalarValue(t dto.MetricType, m *dto.Metric) (float64, bool) {
+	switch t {
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), true
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), true
+	}
+	return 0, false
+}
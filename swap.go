@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/mem"
+)
+
+type swapCollector struct {
+	total, used, usedPercent prometheus.Gauge
+}
+
+func newSwapCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	factory := promauto.With(reg)
+	return &swapCollector{
+		total: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "swap_total_bytes",
+			Help: "Total swap space in bytes",
+		}),
+		used: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "swap_used_bytes",
+			Help: "Used swap space in bytes",
+		}),
+		usedPercent: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "swap_used_percent",
+			Help: "Used swap space in percent",
+		}),
+	}, nil
+}
+
+func (c *swapCollector) Name() string { return "swap" }
+
+func (c *swapCollector) Collect(ctx context.Context) error {
+	swap, err := mem.SwapMemoryWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	c.total.Set(float64(swap.Total))
+	c.used.Set(float64(swap.Used))
+	// Without any swap configured there is nothing to be a percentage of;
+	// report zero instead of whatever the platform computed.
+	if swap.Total == 0 {
+		c.usedPercent.Set(0)
+	} else {
+		c.usedPercent.Set(swap.UsedPercent)
+	}
+	return nil
+}
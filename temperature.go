@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shirou/gopsutil/host"
+)
+
+type temperatureCollector struct {
+	filter      *regexp.Regexp
+	temperature *prometheus.GaugeVec
+	known       map[string]bool
+}
+
+func newTemperatureCollector(cfg *config, reg prometheus.Registerer) (Collector, error) {
+	c := &temperatureCollector{known: map[string]bool{}}
+	if cfg.TempSensorFilter != "" {
+		c.filter = regexp.MustCompile(cfg.TempSensorFilter)
+	}
+	// Many hosts expose no sensors at all, or only fail to read them; skip
+	// the collector up front rather than reporting the same error forever.
+	err := probe(cfg, c.Name(), func(ctx context.Context) error {
+		sensors, err := c.sensors(ctx)
+		if len(sensors) > 0 {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("no temperature sensors found")
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnsupported, err)
+	}
+	c.temperature = promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sensor_temperature_celsius",
+		Help: "Temperature reported by a hardware sensor in degrees Celsius",
+	}, []string{"sensor"})
+	return c, nil
+}
+
+func (c *temperatureCollector) Name() string { return "temperature" }
+
+func (c *temperatureCollector) Collect(ctx context.Context) error {
+	sensors, err := c.sensors(ctx)
+	// Some sensors failing to read still leaves the others usable, so only
+	// give up when nothing came back.
+	if len(sensors) == 0 && err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, s := range sensors {
+		seen[s.SensorKey] = true
+		c.temperature.WithLabelValues(s.SensorKey).Set(s.Temperature)
+	}
+	for key := range c.known {
+		if !seen[key] {
+			c.temperature.DeleteLabelValues(key)
+		}
+	}
+	c.known = seen
+	return nil
+}
+
+// sensors returns the readings of the sensors matching the filter.
+func (c *temperatureCollector) sensors(ctx context.Context) ([]host.TemperatureStat, error) {
+	all, err := host.SensorsTemperaturesWithContext(ctx)
+	if c.filter == nil {
+		return all, err
+	}
+	var matched []host.TemperatureStat
+	for _, s := range all {
+		if c.filter.MatchString(s.SensorKey) {
+			matched = append(matched, s)
+		}
+	}
+	return matched, err
+}
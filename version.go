@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Set at build time with
+//
+//	go build -ldflags "-X main.version=1.2.3 -X main.commit=$(git rev-parse HEAD)"
+var (
+	version = "dev"
+	commit  = "unknown"
+)
+
+func versionString() string {
+	return fmt.Sprintf("observator %s (commit %s, %s)", version, commit, runtime.Version())
+}
+
+func registerBuildInfo(reg prometheus.Registerer) {
+	promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "observator_build_info",
+		Help: "Build information about observator; the value is always 1",
+	}, []string{"version", "commit", "go_version"}).WithLabelValues(version, commit, runtime.Version()).Set(1)
+}